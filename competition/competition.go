@@ -1,154 +1,111 @@
 package competition
 
 import (
-	"fmt"
-	"log"
-	"os"
+	"errors"
 	"sort"
 	"time"
 
 	"github.com/ironywer/sunny_5_skiers/config"
 	"github.com/ironywer/sunny_5_skiers/event"
+	"github.com/ironywer/sunny_5_skiers/registry"
 )
 
 type Competitor struct {
 	ID               int
+	TeamId           int // команда эстафеты, 0 — личная гонка
+	Leg              int // номер этапа эстафеты, с 1
+	Athlete          *registry.Athlete
+	State            State
 	RegisteredAt     time.Duration
 	ScheduledAt      time.Duration
 	ActualStart      time.Duration
 	Started          bool
 	NotStarted       bool
 	NotFinished      bool
+	NotReached       bool // этап эстафеты не начат: команда сошла до передачи
+	Finished         bool
+	FinishedAt       time.Duration
 	LapsDone         int
 	CurrentLapAt     time.Duration
 	LapTimes         []time.Duration
 	PenaltyCount     int
 	PenaltyStartedAt time.Duration
 	PenaltyTime      time.Duration
+	PenaltyVisits    []PenaltyVisit
+	PenaltyCheck     *PenaltyCheck // заполняется на финише
+	MissPenalty      time.Duration // штрафное время за промахи (scoring: time)
 	Hits             int
 	Shots            int
+	Spares           int
+	Bouts            []Bout
+	CourseIssues     []CourseIssue
 
 	OutgoingEvents []OutgoingEvent
 }
 
-type OutgoingEvent struct {
-	Time    time.Duration
-	EventId int
+// Category возвращает категорию участника из реестра или пустую строку.
+func (c *Competitor) Category() string {
+	if c.Athlete == nil {
+		return ""
+	}
+	return c.Athlete.Category
 }
 
-func ProcessEvents(cfg *config.Config, events []event.Event) map[int]*Competitor {
-
-	f, err := os.OpenFile("events.log",
-		os.O_CREATE|os.O_WRONLY|os.O_APPEND,
-		0o666)
-	if err != nil {
-		log.Fatalf("не удалось открыть файл лога: %v", err)
+// TotalTime — итоговое время: опоздание относительно жеребьёвки,
+// сумма кругов, время штрафных кругов, штраф за непройденные круги
+// и штрафное время за промахи.
+func (c *Competitor) TotalTime() time.Duration {
+	tot := c.ActualStart - c.ScheduledAt
+	for _, lap := range c.LapTimes {
+		tot += lap
 	}
-	defer f.Close()
+	return tot + c.PenaltyTime + c.timePenalty()
+}
 
-	log.SetOutput(f)
+// timePenalty — штрафное время сверх пройденной дистанции: за промахи
+// при scoring: time и за непройденные штрафные круги.
+func (c *Competitor) timePenalty() time.Duration {
+	p := c.MissPenalty
+	if c.PenaltyCheck != nil {
+		p += c.PenaltyCheck.TimePenalty
+	}
+	return p
+}
 
-	log.SetFlags(0)
-	log.SetPrefix("")
+type OutgoingEvent struct {
+	Time    time.Duration
+	EventId int
+}
 
-	competitors := make(map[int]*Competitor)
+func ProcessEvents(cfg *config.Config, events []event.Event, logger EventLogger) map[int]*Competitor {
 
-	sort.Slice(events, func(i, j int) bool {
+	sort.SliceStable(events, func(i, j int) bool {
 		return events[i].Fixtime < events[j].Fixtime
 	})
 
+	race := NewRace(cfg, logger)
 	for _, ev := range events {
-		c, ok := competitors[ev.CompetitorId]
-		if !ok {
-			c = &Competitor{ID: ev.CompetitorId}
-			competitors[c.ID] = c
-		}
-
-		switch ev.EventId {
-		case 1: // регистрация
-			c.RegisteredAt = ev.Fixtime
-			log.Printf("[%s] The competitor(%d) registered\n",
-				config.FormatClock(ev.Fixtime), ev.CompetitorId)
-		case 2: // время старта
-			d, err := config.ParseRowForDuration(ev.ExtraParams)
-			if err != nil {
-				panic(fmt.Sprintf("Parse start time: %v", err))
-			}
-			log.Printf("[%s] The start time for the competitor(%d) was set by a draw to %s\n",
-				config.FormatClock(ev.Fixtime), ev.CompetitorId, config.FormatClock(d))
-			c.ScheduledAt = d
-
-		case 3: // на стартовой линии
-			log.Printf("[%s] The competitor(%d) is on the start line\n",
-				config.FormatClock(ev.Fixtime), ev.CompetitorId)
-		case 4: // выход на трассу
-			c.ActualStart = ev.Fixtime
-			c.Started = true
-
-			if ev.Fixtime > c.ScheduledAt+cfg.StartDelta {
-				c.NotStarted = true
-				c.OutgoingEvents = append(c.OutgoingEvents,
-					OutgoingEvent{Time: ev.Fixtime, EventId: 32})
-				log.Printf("[%s] The competitor(%d) was disqualified\n",
-					config.FormatClock(ev.Fixtime), ev.CompetitorId)
-			} else {
-				c.CurrentLapAt = ev.Fixtime
-				log.Printf("[%s] The competitor(%d) has started\n",
-					config.FormatClock(ev.Fixtime), ev.CompetitorId)
-			}
+		race.Apply(ev) // события, которые нельзя применить, пропускаются
+	}
 
-		case 5: // вход на стрельбище
-			log.Printf("[%s] The competitor(%d) is on the firing range\n",
-				config.FormatClock(ev.Fixtime), ev.CompetitorId)
-		case 6: // попадание
-			c.Hits++
-			c.Shots++
-			targetId := ev.ExtraParams
-			log.Printf("[%s] The target(%s) has been hit by competitor(%d)\n",
-				config.FormatClock(ev.Fixtime), targetId, ev.CompetitorId)
-		case 7: // уход с стрельбища
-			log.Printf("[%s] The competitor(%d) left the firing range\n",
-				config.FormatClock(ev.Fixtime), ev.CompetitorId)
+	race.Finalize()
+	return race.Competitors()
+}
 
-		case 8: // заход на штрафные круги
-			c.PenaltyCount++
-			c.PenaltyStartedAt = ev.Fixtime
-			log.Printf("[%s] The competitor(%d) entered the penalty laps\n",
-				config.FormatClock(ev.Fixtime), ev.CompetitorId)
-		case 9: // выход со штрафных кругов
-			c.PenaltyTime += ev.Fixtime - c.PenaltyStartedAt
-			log.Printf("[%s] The competitor(%d) left the penalty laps\n",
-				config.FormatClock(ev.Fixtime), ev.CompetitorId)
-		case 10: // конец основного круга
-			lap := ev.Fixtime - c.CurrentLapAt
-			c.LapTimes = append(c.LapTimes, lap)
-			c.CurrentLapAt = ev.Fixtime
-			c.LapsDone++
-			log.Printf("[%s] The competitor(%d) ended the main lap\n",
-				config.FormatClock(ev.Fixtime), ev.CompetitorId)
-			if c.LapsDone == cfg.Laps {
-				c.OutgoingEvents = append(c.OutgoingEvents,
-					OutgoingEvent{Time: ev.Fixtime, EventId: 33})
-				log.Printf("[%s] The competitor(%d) has finished\n",
-					config.FormatClock(ev.Fixtime), ev.CompetitorId)
-			}
+// ProcessReader обрабатывает события по мере чтения. В отличие от
+// ProcessEvents поток не сортируется: события должны идти по времени.
+func ProcessReader(cfg *config.Config, r *event.Reader, logger EventLogger) (map[int]*Competitor, error) {
 
-		case 11: // не может продолжить
-			c.NotFinished = true
-			c.ActualStart = ev.Fixtime
-			log.Printf("[%s] The competitor(%d) can`t continue: %s\n",
-				config.FormatClock(ev.Fixtime), ev.CompetitorId, ev.ExtraParams)
+	race := NewRace(cfg, logger)
+	for ev, err := range r.All() {
+		if err != nil {
+			return nil, err
 		}
-	}
-
-	for _, c := range competitors {
-		if !c.Started && !c.NotStarted {
-			c.NotStarted = true
-			c.ActualStart = c.OutgoingEvents[0].Time
-			disqTime := c.ScheduledAt + cfg.StartDelta
-			c.OutgoingEvents = append(c.OutgoingEvents,
-				OutgoingEvent{Time: disqTime, EventId: 32})
+		if err := race.Apply(ev); err != nil && !errors.Is(err, ErrRejected) {
+			return nil, err
 		}
 	}
-	return competitors
+
+	race.Finalize()
+	return race.Competitors(), nil
 }
@@ -1,13 +1,15 @@
 package competition
 
 import (
-	"os"
+	"errors"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/ironywer/sunny_5_skiers/config"
 	"github.com/ironywer/sunny_5_skiers/event"
+	"github.com/ironywer/sunny_5_skiers/registry"
 )
 
 func mustParse(t *testing.T, s string) time.Duration {
@@ -18,23 +20,25 @@ func mustParse(t *testing.T, s string) time.Duration {
 	return d
 }
 
-func readLog(t *testing.T) string {
-	data, err := os.ReadFile("events.log")
-	if err != nil {
-		t.Fatalf("read log file: %v", err)
+// applyAll применяет события input по порядку; любая ошибка Apply проваливает тест.
+func applyAll(t *testing.T, race *Race, input string) {
+	t.Helper()
+	for ev, err := range event.NewReader(strings.NewReader(input)).All() {
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		if err := race.Apply(ev); err != nil {
+			t.Fatalf("apply %s: %v", ev, err)
+		}
 	}
-	return string(data)
 }
 
-func setupTemp(t *testing.T) {
-	dir := t.TempDir()
-	if err := os.Chdir(dir); err != nil {
-		t.Fatalf("chdir: %v", err)
-	}
+func readLog(l *MemoryLogger) string {
+	return strings.Join(l.Lines(), "\n")
 }
 
 func TestNotStartedBranch(t *testing.T) {
-	setupTemp(t)
+	logger := &MemoryLogger{}
 
 	cfg := &config.Config{
 		Laps:        1,
@@ -50,7 +54,7 @@ func TestNotStartedBranch(t *testing.T) {
 		{Fixtime: mustParse(t, "00:00:00.000"), EventId: 2, CompetitorId: 1, ExtraParams: "00:00:00.000"},
 		{Fixtime: mustParse(t, "00:00:15.000"), EventId: 4, CompetitorId: 1},
 	}
-	comps := ProcessEvents(cfg, evs)
+	comps := ProcessEvents(cfg, evs, logger)
 	c := comps[1]
 
 	if !c.NotStarted {
@@ -60,14 +64,14 @@ func TestNotStartedBranch(t *testing.T) {
 		t.Errorf("expected outgoing 32, got %+v", c.OutgoingEvents)
 	}
 
-	log := readLog(t)
+	log := readLog(logger)
 	if !strings.Contains(log, "was disqualified") {
 		t.Error("log missing disqualification message")
 	}
 }
 
 func TestNotFinishedBranch(t *testing.T) {
-	setupTemp(t)
+	logger := &MemoryLogger{}
 
 	cfg := &config.Config{Laps: 1, LapLen: 100, PenaltyLen: 10, FiringLines: 1, Start: mustParse(t, "00:00:00.000"), StartDelta: mustParse(t, "00:00:10.000")}
 
@@ -77,20 +81,20 @@ func TestNotFinishedBranch(t *testing.T) {
 		{Fixtime: mustParse(t, "00:00:00.000"), EventId: 4, CompetitorId: 2},
 		{Fixtime: mustParse(t, "00:01:00.000"), EventId: 11, CompetitorId: 2, ExtraParams: "failed"},
 	}
-	comps := ProcessEvents(cfg, evs)
+	comps := ProcessEvents(cfg, evs, logger)
 	c := comps[2]
 
 	if !c.NotFinished {
 		t.Errorf("expected NotFinished=true, got false")
 	}
-	log := readLog(t)
+	log := readLog(logger)
 	if !strings.Contains(log, "can`t continue: failed") {
 		t.Error("log missing NotFinished message")
 	}
 }
 
 func TestPenaltyBranch(t *testing.T) {
-	setupTemp(t)
+	logger := &MemoryLogger{}
 
 	cfg := &config.Config{Laps: 1, LapLen: 100, PenaltyLen: 10, FiringLines: 1, Start: mustParse(t, "00:00:00.000"), StartDelta: mustParse(t, "00:00:10.000")}
 
@@ -102,7 +106,7 @@ func TestPenaltyBranch(t *testing.T) {
 		{Fixtime: mustParse(t, "00:00:15.000"), EventId: 9, CompetitorId: 3},
 		{Fixtime: mustParse(t, "00:00:20.000"), EventId: 10, CompetitorId: 3},
 	}
-	comps := ProcessEvents(cfg, evs)
+	comps := ProcessEvents(cfg, evs, logger)
 	c := comps[3]
 
 	if c.PenaltyCount != 1 {
@@ -111,7 +115,7 @@ func TestPenaltyBranch(t *testing.T) {
 	if c.PenaltyTime != mustParse(t, "00:00:10.000") {
 		t.Errorf("expected PenaltyTime=10s, got %v", c.PenaltyTime)
 	}
-	log := readLog(t)
+	log := readLog(logger)
 	if !strings.Contains(log, "entered the penalty laps") ||
 		!strings.Contains(log, "left the penalty laps") {
 		t.Error("log missing penalty messages")
@@ -119,7 +123,7 @@ func TestPenaltyBranch(t *testing.T) {
 }
 
 func TestHitsAndShotsBranch(t *testing.T) {
-	setupTemp(t)
+	logger := &MemoryLogger{}
 
 	cfg := &config.Config{Laps: 1, LapLen: 100, PenaltyLen: 10, FiringLines: 1, Start: mustParse(t, "00:00:00.000"), StartDelta: mustParse(t, "00:00:10.000")}
 
@@ -127,7 +131,7 @@ func TestHitsAndShotsBranch(t *testing.T) {
 		{Fixtime: mustParse(t, "00:00:00.000"), EventId: 1, CompetitorId: 4},
 		{Fixtime: mustParse(t, "00:00:00.000"), EventId: 2, CompetitorId: 4, ExtraParams: "00:00:00.000"},
 		{Fixtime: mustParse(t, "00:00:00.000"), EventId: 4, CompetitorId: 4},
-		{Fixtime: mustParse(t, "00:01:00.000"), EventId: 5, CompetitorId: 4},
+		{Fixtime: mustParse(t, "00:01:00.000"), EventId: 5, CompetitorId: 4, ExtraParams: "1"},
 
 		{Fixtime: mustParse(t, "00:01:01.000"), EventId: 6, CompetitorId: 4, ExtraParams: "1"},
 		{Fixtime: mustParse(t, "00:01:02.000"), EventId: 6, CompetitorId: 4, ExtraParams: "2"},
@@ -135,20 +139,20 @@ func TestHitsAndShotsBranch(t *testing.T) {
 		{Fixtime: mustParse(t, "00:01:04.000"), EventId: 7, CompetitorId: 4},
 		{Fixtime: mustParse(t, "00:02:00.000"), EventId: 10, CompetitorId: 4},
 	}
-	comps := ProcessEvents(cfg, evs)
+	comps := ProcessEvents(cfg, evs, logger)
 	c := comps[4]
 
-	if c.Hits != 3 || c.Shots != 3 {
-		t.Errorf("expected 3/3 hits/shots, got %d/%d", c.Hits, c.Shots)
+	if c.Hits != 3 || c.Shots != 5 {
+		t.Errorf("expected 3/5 hits/shots, got %d/%d", c.Hits, c.Shots)
 	}
-	log := readLog(t)
+	log := readLog(logger)
 	if !strings.Contains(log, "has been hit by competitor(4)") {
 		t.Error("log missing hit messages")
 	}
 }
 
 func TestMultipleLapsBranch(t *testing.T) {
-	setupTemp(t)
+	logger := &MemoryLogger{}
 
 	cfg := &config.Config{Laps: 2, LapLen: 100, PenaltyLen: 10, FiringLines: 1, Start: mustParse(t, "00:00:00.000"), StartDelta: mustParse(t, "00:00:05.000")}
 
@@ -159,7 +163,7 @@ func TestMultipleLapsBranch(t *testing.T) {
 		{Fixtime: mustParse(t, "00:01:00.000"), EventId: 10, CompetitorId: 5},
 		{Fixtime: mustParse(t, "00:02:30.000"), EventId: 10, CompetitorId: 5},
 	}
-	comps := ProcessEvents(cfg, evs)
+	comps := ProcessEvents(cfg, evs, logger)
 	c := comps[5]
 
 	if len(c.LapTimes) != 2 {
@@ -169,8 +173,753 @@ func TestMultipleLapsBranch(t *testing.T) {
 	if c.OutgoingEvents[len(c.OutgoingEvents)-1].EventId != 33 {
 		t.Errorf("expected final EventId=33, got %d", c.OutgoingEvents[len(c.OutgoingEvents)-1].EventId)
 	}
-	log := readLog(t)
+	log := readLog(logger)
 	if !strings.Contains(log, "has finished") {
 		t.Error("log missing final finish message")
 	}
 }
+
+func TestProcessReader(t *testing.T) {
+	logger := &MemoryLogger{}
+
+	cfg := &config.Config{Laps: 1, LapLen: 100, PenaltyLen: 10, FiringLines: 1, Start: mustParse(t, "00:00:00.000"), StartDelta: mustParse(t, "00:00:10.000")}
+
+	input := `[00:00:00.000] 1 6
+[00:00:00.000] 2 6 00:00:00.000
+[00:00:01.000] 4 6
+[00:01:00.000] 10 6`
+	comps, err := ProcessReader(cfg, event.NewReader(strings.NewReader(input)), logger)
+	if err != nil {
+		t.Fatalf("ProcessReader returned error: %v", err)
+	}
+	c := comps[6]
+	if len(c.LapTimes) != 1 || c.LapTimes[0] != mustParse(t, "00:00:59.000") {
+		t.Errorf("unexpected lap times %v", c.LapTimes)
+	}
+	if !strings.Contains(readLog(logger), "The competitor(6) has finished") {
+		t.Error("log missing finish message")
+	}
+}
+
+func TestProcessEventsKeepsOrderOfSimultaneous(t *testing.T) {
+	cfg := &config.Config{Laps: 1, LapLen: 100, PenaltyLen: 10, FiringLines: 1, Start: mustParse(t, "00:00:00.000"), StartDelta: mustParse(t, "00:00:10.000")}
+
+	// Регистрация, время старта и старт в одно мгновение: порядок важен.
+	var evs []event.Event
+	for id := 1; id <= 40; id++ {
+		evs = append(evs,
+			event.Event{Fixtime: 0, EventId: 1, CompetitorId: id},
+			event.Event{Fixtime: 0, EventId: 2, CompetitorId: id, ExtraParams: "00:00:00.000"},
+			event.Event{Fixtime: 0, EventId: 3, CompetitorId: id},
+			event.Event{Fixtime: 0, EventId: 4, CompetitorId: id},
+			event.Event{Fixtime: time.Minute, EventId: 10, CompetitorId: id},
+		)
+	}
+	logger := &MemoryLogger{}
+	comps := ProcessEvents(cfg, evs, logger)
+	for id, c := range comps {
+		if c.State != StateFinished {
+			t.Errorf("competitor %d: state %s; want Finished", id, c.State)
+		}
+	}
+	if log := readLog(logger); strings.Contains(log, "out of sequence") {
+		t.Errorf("unexpected anomalies:\n%s", log)
+	}
+}
+
+func TestRaceStandingsAndFinalize(t *testing.T) {
+
+	cfg := &config.Config{Laps: 2, LapLen: 100, PenaltyLen: 10, FiringLines: 1, Start: mustParse(t, "00:00:00.000"), StartDelta: mustParse(t, "00:00:10.000")}
+	race := NewRace(cfg, nil)
+
+	evs := []event.Event{
+		{Fixtime: mustParse(t, "00:00:00.000"), EventId: 2, CompetitorId: 1, ExtraParams: "00:00:00.000"},
+		{Fixtime: mustParse(t, "00:00:00.000"), EventId: 2, CompetitorId: 2, ExtraParams: "00:00:30.000"},
+		{Fixtime: mustParse(t, "00:00:00.000"), EventId: 2, CompetitorId: 3, ExtraParams: "00:01:00.000"},
+		{Fixtime: mustParse(t, "00:00:01.000"), EventId: 4, CompetitorId: 1},
+		{Fixtime: mustParse(t, "00:00:31.000"), EventId: 4, CompetitorId: 2},
+		{Fixtime: mustParse(t, "00:01:05.000"), EventId: 10, CompetitorId: 2},
+	}
+	for _, ev := range evs {
+		if err := race.Apply(ev); err != nil {
+			t.Fatalf("apply %s: %v", ev, err)
+		}
+	}
+
+	st := race.Standings()
+	if st[0].ID != 2 || st[1].ID != 1 || st[2].ID != 3 {
+		t.Errorf("unexpected live standings order: %d, %d, %d", st[0].ID, st[1].ID, st[2].ID)
+	}
+	if race.Competitors()[3].NotStarted {
+		t.Error("competitor 3 must not be NotStarted before the start deadline")
+	}
+
+	race.Apply(event.Event{Fixtime: mustParse(t, "00:02:00.000"), EventId: 10, CompetitorId: 1})
+	c3 := race.Competitors()[3]
+	if !c3.NotStarted {
+		t.Error("competitor 3 must be NotStarted once the start deadline has passed")
+	}
+	race.Apply(event.Event{Fixtime: mustParse(t, "00:03:00.000"), EventId: 10, CompetitorId: 1})
+	race.Finalize()
+	race.Finalize()
+
+	if !c3.NotStarted || len(c3.OutgoingEvents) != 1 || c3.OutgoingEvents[0].Time != mustParse(t, "00:01:10.000") {
+		t.Errorf("expected a single NotStarted disqualification at 00:01:10, got %+v", c3.OutgoingEvents)
+	}
+	if c2 := race.Competitors()[2]; !c2.NotFinished || c2.State != StateNotFinished {
+		t.Errorf("competitor 2 still on track must be NotFinished after Finalize, got %+v", c2)
+	}
+	st = race.Standings()
+	if st[0].ID != 1 || !st[0].Finished || st[2].ID != 3 {
+		t.Errorf("unexpected final standings order: %d, %d, %d", st[0].ID, st[1].ID, st[2].ID)
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	cfg := &config.Config{Laps: 1, LapLen: 100, PenaltyLen: 10, FiringLines: 1, StartDelta: mustParse(t, "00:00:10.000"), AnomalyPolicy: config.PolicyReject}
+	race := NewRace(cfg, nil)
+
+	if err := race.Apply(event.Event{Fixtime: mustParse(t, "00:00:05.000"), EventId: 2, CompetitorId: 1, ExtraParams: "soon"}); err == nil {
+		t.Error("expected an error for a malformed start time")
+	}
+	if _, ok := race.Competitors()[1]; ok {
+		t.Error("malformed event must not create a competitor")
+	}
+	if err := race.Apply(event.Event{Fixtime: mustParse(t, "00:00:05.000"), EventId: 1, CompetitorId: 1}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	if err := race.Apply(event.Event{Fixtime: mustParse(t, "00:00:04.000"), EventId: 1, CompetitorId: 2}); !errors.Is(err, ErrOutOfOrder) {
+		t.Errorf("event back in time: err = %v; want ErrOutOfOrder", err)
+	}
+	if err := race.Apply(event.Event{Fixtime: mustParse(t, "00:00:06.000"), EventId: 10, CompetitorId: 1}); !errors.Is(err, ErrRejected) {
+		t.Errorf("out-of-sequence event: err = %v; want ErrRejected", err)
+	}
+	race.Finalize()
+	if err := race.Apply(event.Event{Fixtime: mustParse(t, "00:00:07.000"), EventId: 1, CompetitorId: 3}); !errors.Is(err, ErrFinalized) {
+		t.Errorf("event after Finalize: err = %v; want ErrFinalized", err)
+	}
+}
+
+func TestRejectedEventLeavesRaceUnchanged(t *testing.T) {
+	cfg := &config.Config{Laps: 1, LapLen: 100, PenaltyLen: 10, FiringLines: 1, StartDelta: mustParse(t, "00:00:10.000"), AnomalyPolicy: config.PolicyReject}
+	var buf strings.Builder
+	race := NewRace(cfg, nil)
+	race.SetOutput(event.NewWriter(&buf))
+
+	for _, ev := range []event.Event{
+		{Fixtime: mustParse(t, "00:00:00.000"), EventId: 1, CompetitorId: 1},
+		{Fixtime: mustParse(t, "00:00:00.000"), EventId: 2, CompetitorId: 1, ExtraParams: "00:01:00.000"},
+	} {
+		if err := race.Apply(ev); err != nil {
+			t.Fatalf("apply %s: %v", ev, err)
+		}
+	}
+	before := buf.String()
+
+	// неизвестный участник и опечатка во времени: оба события отклоняются
+	for _, ev := range []event.Event{
+		{Fixtime: mustParse(t, "00:00:05.000"), EventId: 5, CompetitorId: 99},
+		{Fixtime: mustParse(t, "01:00:00.000"), EventId: 5, CompetitorId: 1},
+	} {
+		if err := race.Apply(ev); !errors.Is(err, ErrRejected) {
+			t.Errorf("apply %s: err = %v; want ErrRejected", ev, err)
+		}
+	}
+	if _, ok := race.Competitors()[99]; ok || len(race.Competitors()) != 1 {
+		t.Errorf("rejected event created a competitor: %v", race.Competitors())
+	}
+	if buf.String() != before {
+		t.Errorf("rejected events changed the stream: %q", buf.String())
+	}
+	// часы гонки не сдвинулись: событие раньше отклонённого принимается,
+	// а участник 1 не дисквалифицирован
+	if err := race.Apply(event.Event{Fixtime: mustParse(t, "00:01:00.000"), EventId: 4, CompetitorId: 1}); err != nil {
+		t.Fatalf("start after rejected events: %v", err)
+	}
+	if c := race.Competitors()[1]; c.State != StateOnTrack {
+		t.Errorf("state = %s; want OnTrack", c.State)
+	}
+}
+
+func TestLoggers(t *testing.T) {
+	var buf strings.Builder
+	mem := &MemoryLogger{}
+	logger := MultiLogger(NewWriterLogger(&buf), mem)
+
+	logger.Log(mustParse(t, "09:05:59.867"), "The competitor(1) registered")
+
+	want := "[09:05:59.867] The competitor(1) registered"
+	if buf.String() != want+"\n" {
+		t.Errorf("writer got %q; want %q", buf.String(), want)
+	}
+	if lines := mem.Lines(); len(lines) != 1 || lines[0] != want {
+		t.Errorf("memory got %q; want %q", lines, want)
+	}
+}
+
+func TestRaceOutputStream(t *testing.T) {
+	cfg := &config.Config{Laps: 1, LapLen: 100, PenaltyLen: 10, FiringLines: 1, Start: mustParse(t, "00:00:00.000"), StartDelta: mustParse(t, "00:00:10.000")}
+
+	var buf strings.Builder
+	race := NewRace(cfg, nil)
+	race.SetOutput(event.NewWriter(&buf))
+
+	input := `[00:00:00.000] 2 1 00:00:00.000
+[00:00:00.000] 2 2 00:01:00.000
+[00:00:00.000] 2 3 00:00:20.000
+[00:00:01.000] 4 1
+[00:01:00.000] 10 1`
+	applyAll(t, race, input)
+	race.Finalize()
+
+	// 32 для 3 — перед первым событием после его крайнего срока,
+	// для 2 срок не истёк до конца потока — в Finalize.
+	want := `[00:00:00.000] 2 1 00:00:00.000
+[00:00:00.000] 2 2 00:01:00.000
+[00:00:00.000] 2 3 00:00:20.000
+[00:00:01.000] 4 1
+[00:00:30.000] 32 3
+[00:01:00.000] 10 1
+[00:01:00.000] 33 1
+[00:01:10.000] 32 2
+`
+	if race.Err() != nil || buf.String() != want {
+		t.Errorf("stream = %q (err %v); want %q", buf.String(), race.Err(), want)
+	}
+}
+
+func TestAnomalyPolicies(t *testing.T) {
+	evs := []event.Event{
+		{Fixtime: mustParse(t, "00:00:00.000"), EventId: 1, CompetitorId: 7},
+		{Fixtime: mustParse(t, "00:00:00.000"), EventId: 2, CompetitorId: 7, ExtraParams: "00:00:00.000"},
+		{Fixtime: mustParse(t, "00:00:01.000"), EventId: 4, CompetitorId: 7},
+		{Fixtime: mustParse(t, "00:00:10.000"), EventId: 6, CompetitorId: 7, ExtraParams: "1"},
+		{Fixtime: mustParse(t, "00:00:20.000"), EventId: 9, CompetitorId: 7},
+		{Fixtime: mustParse(t, "00:01:00.000"), EventId: 10, CompetitorId: 7},
+		{Fixtime: mustParse(t, "00:01:05.000"), EventId: 10, CompetitorId: 7},
+	}
+
+	tests := []struct {
+		policy    string
+		anomalies int
+		laps      int
+	}{
+		{config.PolicyIgnore, 0, 2},
+		{"", 3, 2},
+		{config.PolicyWarn, 3, 2},
+		{config.PolicyReject, 3, 1},
+	}
+	for _, tt := range tests {
+		cfg := &config.Config{Laps: 1, LapLen: 100, PenaltyLen: 10, FiringLines: 1, StartDelta: mustParse(t, "00:00:10.000"), AnomalyPolicy: tt.policy}
+		logger := &MemoryLogger{}
+		race := NewRace(cfg, logger)
+		for _, ev := range evs {
+			if err := race.Apply(ev); err != nil && !errors.Is(err, ErrRejected) {
+				t.Fatalf("policy %q: apply %s: %v", tt.policy, ev, err)
+			}
+		}
+		c := race.Competitors()[7]
+		if len(race.Anomalies()) != tt.anomalies || c.LapsDone != tt.laps {
+			t.Errorf("policy %q: got %d anomalies, %d laps; want %d, %d",
+				tt.policy, len(race.Anomalies()), c.LapsDone, tt.anomalies, tt.laps)
+		}
+		if tt.anomalies == 0 {
+			continue
+		}
+		a := race.Anomalies()[0]
+		if a.CompetitorId != 7 || a.EventId != 6 || a.State != StateOnTrack ||
+			len(a.Expected) != 1 || a.Expected[0] != StateOnRange {
+			t.Errorf("policy %q: unexpected first anomaly %+v", tt.policy, a)
+		}
+		if !strings.Contains(readLog(logger), "The event(6) of competitor(7) is out of sequence: state OnTrack, expected OnRange") {
+			t.Errorf("policy %q: log missing anomaly message", tt.policy)
+		}
+	}
+}
+
+func TestShootingCard(t *testing.T) {
+	cfg := &config.Config{Laps: 2, LapLen: 100, PenaltyLen: 10, FiringLines: 1, StartDelta: mustParse(t, "00:00:10.000"), TargetsPerBout: 5}
+	logger := &MemoryLogger{}
+	race := NewRace(cfg, logger)
+
+	input := `[00:00:00.000] 1 8
+[00:00:00.000] 2 8 00:00:00.000
+[00:00:01.000] 4 8
+[00:00:30.000] 5 8 1
+[00:00:31.000] 6 8 1
+[00:00:32.000] 6 8 2
+[00:00:33.000] 6 8 2
+[00:00:34.000] 7 8
+[00:01:00.000] 10 8
+[00:01:30.000] 5 8 2
+[00:01:31.000] 6 8 5
+[00:01:32.000] 6 8 6
+[00:01:33.000] 6 8 x
+[00:01:34.000] 7 8`
+	applyAll(t, race, input)
+	c := race.Competitors()[8]
+
+	if len(c.Bouts) != 2 {
+		t.Fatalf("expected 2 bouts, got %d", len(c.Bouts))
+	}
+	b := c.Bouts[0]
+	if b.Range != 1 || b.Lap != 1 || b.Hits() != 2 || b.Misses() != 3 ||
+		b.EnteredAt != mustParse(t, "00:00:30.000") || b.LeftAt != mustParse(t, "00:00:34.000") {
+		t.Errorf("unexpected first bout %+v", b)
+	}
+	b = c.Bouts[1]
+	if b.Range != 2 || b.Lap != 2 || b.Hits() != 1 || b.Misses() != 4 {
+		t.Errorf("unexpected second bout %+v", b)
+	}
+	if c.Hits != 3 || c.Shots != 10 || c.Misses() != 7 {
+		t.Errorf("expected 3/10 with 7 misses, got %d/%d with %d", c.Hits, c.Shots, c.Misses())
+	}
+	if !strings.Contains(readLog(logger), "[00:01:32.000] The target(6) hit by competitor(8) is invalid and was not counted") ||
+		!strings.Contains(readLog(logger), "The target(x) hit by competitor(8) is invalid") {
+		t.Error("log missing invalid target messages")
+	}
+}
+
+func TestFiringLinesCheck(t *testing.T) {
+	cfg := &config.Config{Laps: 3, LapLen: 100, PenaltyLen: 10, FiringLines: 2, StartDelta: mustParse(t, "00:00:10.000")}
+	logger := &MemoryLogger{}
+	race := NewRace(cfg, logger)
+
+	input := `[00:00:00.000] 1 9
+[00:00:00.000] 2 9 00:00:00.000
+[00:00:01.000] 4 9
+[00:00:20.000] 5 9 1
+[00:00:25.000] 7 9
+[00:00:40.000] 5 9 2
+[00:00:45.000] 7 9
+[00:01:00.000] 10 9
+[00:01:40.000] 5 9 3
+[00:01:45.000] 7 9
+[00:02:00.000] 10 9
+[00:03:00.000] 10 9`
+	applyAll(t, race, input)
+	c := race.Competitors()[9]
+
+	// один выстрел за круг на рубеже 1..FiringLines: как в примере,
+	// где на первом круге рубеж 1, на втором — рубеж 2
+	want := []CourseIssue{
+		{Time: mustParse(t, "00:01:00.000"), Lap: 1, Kind: IssueExtraBout, Range: 2},
+		{Time: mustParse(t, "00:02:00.000"), Lap: 2, Kind: IssueUnknownRange, Range: 3},
+		{Time: mustParse(t, "00:03:00.000"), Lap: 3, Kind: IssueSkippedBout},
+	}
+	if !reflect.DeepEqual(c.CourseIssues, want) {
+		t.Errorf("course issues = %+v; want %+v", c.CourseIssues, want)
+	}
+	log := readLog(logger)
+	if !strings.Contains(log, "The competitor(9) made an extra bout on the firing range(2) on lap(1)") ||
+		!strings.Contains(log, "The competitor(9) shot on an unknown firing range(3) on lap(2)") ||
+		!strings.Contains(log, "The competitor(9) skipped the firing range on lap(3)") {
+		t.Error("log missing course issue messages")
+	}
+}
+
+func TestPenaltyCompliance(t *testing.T) {
+	cfg := &config.Config{
+		Laps: 1, LapLen: 100, PenaltyLen: 150, FiringLines: 1,
+		StartDelta:         mustParse(t, "00:00:10.000"),
+		PenaltyLoopSpeed:   3,
+		UnskiedLoopPenalty: mustParse(t, "00:02:00.000"),
+	}
+	logger := &MemoryLogger{}
+	race := NewRace(cfg, logger)
+
+	// 4 промаха, один заход на 100 секунд — два круга по 50 секунд
+	input := `[00:00:00.000] 1 10
+[00:00:00.000] 2 10 00:00:00.000
+[00:00:00.000] 4 10
+[00:01:00.000] 5 10 1
+[00:01:01.000] 6 10 3
+[00:01:05.000] 7 10
+[00:01:10.000] 8 10
+[00:02:50.000] 9 10
+[00:05:00.000] 10 10`
+	applyAll(t, race, input)
+	c := race.Competitors()[10]
+
+	if len(c.PenaltyVisits) != 1 || c.PenaltyVisits[0].Loops != 2 ||
+		c.PenaltyVisits[0].Duration() != mustParse(t, "00:01:40.000") {
+		t.Errorf("unexpected penalty visits %+v", c.PenaltyVisits)
+	}
+	want := PenaltyCheck{Misses: 4, Loops: 2, Unskied: 2, TimePenalty: mustParse(t, "00:04:00.000")}
+	if c.PenaltyCheck == nil || *c.PenaltyCheck != want {
+		t.Errorf("penalty check = %+v; want %+v", c.PenaltyCheck, want)
+	}
+	if c.TotalTime() != mustParse(t, "00:10:40.000") {
+		t.Errorf("total time = %v; want 10m40s", c.TotalTime())
+	}
+	if !strings.Contains(readLog(logger), "The competitor(10) skied 2 penalty laps for 4 misses") {
+		t.Error("log missing penalty discrepancy message")
+	}
+
+	// без PenaltyLoopSpeed число кругов за заход неизвестно: заход не проверяется,
+	// а пропуск штрафных кругов целиком — проверяется
+	cfg.PenaltyLoopSpeed = 0
+	logger = &MemoryLogger{}
+	race = NewRace(cfg, logger)
+	input = `[00:00:00.000] 1 10
+[00:00:00.000] 1 11
+[00:00:00.000] 2 10 00:00:00.000
+[00:00:00.000] 2 11 00:00:00.000
+[00:00:00.000] 4 10
+[00:00:00.000] 4 11
+[00:01:00.000] 5 10 1
+[00:01:00.000] 5 11 1
+[00:01:01.000] 6 10 3
+[00:01:05.000] 7 10
+[00:01:05.000] 7 11
+[00:01:10.000] 8 10
+[00:02:50.000] 9 10
+[00:05:00.000] 10 10
+[00:05:00.000] 10 11`
+	applyAll(t, race, input)
+	if c := race.Competitors()[10]; c.PenaltyCheck != nil {
+		t.Errorf("penalty check without loop speed = %+v; want nil", c.PenaltyCheck)
+	}
+	want = PenaltyCheck{Misses: 5, Unskied: 5, TimePenalty: mustParse(t, "00:10:00.000")}
+	if c := race.Competitors()[11]; c.PenaltyCheck == nil || *c.PenaltyCheck != want {
+		t.Errorf("penalty check without visits = %+v; want %+v", c.PenaltyCheck, want)
+	}
+	if strings.Contains(readLog(logger), "competitor(10) skied") {
+		t.Error("unexpected discrepancy message without loop speed")
+	}
+}
+
+func TestTimeScoring(t *testing.T) {
+	cfg := &config.Config{
+		Laps: 1, LapLen: 100, PenaltyLen: 150, FiringLines: 1,
+		StartDelta:  mustParse(t, "00:00:10.000"),
+		Scoring:     config.ScoringTime,
+		MissPenalty: mustParse(t, "00:00:45.000"),
+	}
+	race := NewRace(cfg, nil)
+
+	input := `[00:00:00.000] 1 11
+[00:00:00.000] 2 11 00:00:00.000
+[00:00:00.000] 4 11
+[00:01:00.000] 5 11 1
+[00:01:01.000] 6 11 1
+[00:01:02.000] 6 11 2
+[00:01:03.000] 6 11 3
+[00:01:05.000] 7 11
+[00:05:00.000] 10 11`
+	applyAll(t, race, input)
+	c := race.Competitors()[11]
+
+	if c.MissPenalty != mustParse(t, "00:01:30.000") {
+		t.Errorf("miss penalty = %v; want 1m30s", c.MissPenalty)
+	}
+	if c.PenaltyCheck != nil {
+		t.Errorf("penalty loop check must be skipped in time scoring, got %+v", c.PenaltyCheck)
+	}
+	if c.TotalTime() != mustParse(t, "00:06:30.000") {
+		t.Errorf("total time = %v; want 6m30s", c.TotalTime())
+	}
+}
+
+func TestMassStart(t *testing.T) {
+	cfg := &config.Config{
+		Laps: 1, LapLen: 100, PenaltyLen: 150, FiringLines: 0,
+		Start:      mustParse(t, "10:00:00.000"),
+		StartDelta: mustParse(t, "00:00:10.000"),
+		Format:     config.FormatMass,
+	}
+	race := NewRace(cfg, nil)
+
+	input := `[09:30:00.000] 1 1
+[09:30:00.000] 1 2
+[09:30:00.000] 1 3
+[10:00:00.000] 4 1
+[10:00:40.000] 4 2
+[10:10:00.000] 10 2
+[10:10:00.500] 10 1`
+	applyAll(t, race, input)
+	race.Finalize()
+
+	if len(race.Anomalies()) != 0 {
+		t.Errorf("unexpected anomalies %v", race.Anomalies())
+	}
+	comps := race.Competitors()
+	if comps[2].NotStarted {
+		t.Error("late starter must not be disqualified in a mass start")
+	}
+	if !comps[3].NotStarted || comps[3].OutgoingEvents[0].Time != cfg.Start {
+		t.Errorf("expected competitor 3 NotStarted at race start, got %+v", comps[3])
+	}
+	st := race.Standings()
+	if st[0].ID != 2 || st[1].ID != 1 || st[2].ID != 3 {
+		t.Errorf("expected finish order 2, 1, 3; got %d, %d, %d", st[0].ID, st[1].ID, st[2].ID)
+	}
+
+	// штрафное время сдвигает место так же, как итоговое время
+	comps[2].MissPenalty = time.Second
+	st = race.Standings()
+	if st[0].ID != 1 || st[1].ID != 2 || st[0].TotalTime() > st[1].TotalTime() {
+		t.Errorf("expected order 1, 2 with a miss penalty for 2; got %d, %d", st[0].ID, st[1].ID)
+	}
+}
+
+func TestPursuit(t *testing.T) {
+	cfg := &config.Config{
+		Laps: 1, LapLen: 100, PenaltyLen: 150,
+		Start:      mustParse(t, "12:00:00.000"),
+		StartDelta: mustParse(t, "00:00:30.000"),
+		Format:     config.FormatPursuit,
+	}
+	race := NewRace(cfg, nil)
+
+	input := `[11:30:00.000] 1 1
+[11:30:00.000] 1 2
+[11:30:00.000] 2 1 12:00:00.000
+[11:30:00.000] 2 2 12:01:00.000
+[12:00:00.000] 4 1
+[12:02:00.000] 4 2
+[12:10:00.000] 10 2
+[12:10:30.000] 10 1`
+	applyAll(t, race, input)
+	race.Finalize()
+
+	comps := race.Competitors()
+	if comps[2].NotStarted {
+		t.Error("late start must not disqualify in a pursuit")
+	}
+	if comps[2].ScheduledAt != mustParse(t, "12:01:00.000") {
+		t.Errorf("start offset must come from seeding, got %v", comps[2].ScheduledAt)
+	}
+	st := race.Standings()
+	if st[0].ID != 2 || st[1].ID != 1 {
+		t.Errorf("expected crossing order 2, 1; got %d, %d", st[0].ID, st[1].ID)
+	}
+}
+
+func TestRelay(t *testing.T) {
+	cfg := &config.Config{
+		Laps: 1, LapLen: 100, PenaltyLen: 150,
+		Start:      mustParse(t, "10:00:00.000"),
+		StartDelta: mustParse(t, "00:00:30.000"),
+		Format:     config.FormatRelay,
+		Teams: []config.Team{
+			{ID: 1, Name: "Norway", Legs: []int{11, 12}},
+			{ID: 2, Name: "Sweden", Legs: []int{21, 22}},
+			{ID: 3, Name: "Finland", Legs: []int{31, 32}},
+			{ID: 4, Name: "Denmark", Legs: []int{41, 42}},
+		},
+	}
+	logger := &MemoryLogger{}
+	race := NewRace(cfg, logger)
+
+	input := `[09:30:00.000] 1 11
+[09:30:00.000] 1 12
+[09:30:00.000] 1 21
+[09:30:00.000] 1 22
+[09:30:00.000] 1 31
+[09:30:00.000] 1 32
+[09:30:00.000] 1 41
+[10:00:00.000] 4 11
+[10:00:00.000] 4 21
+[10:00:00.000] 4 31
+[10:00:00.000] 4 41`
+	applyAll(t, race, input)
+	// второй этап Дании ещё не зарегистрирован — команда на трассе
+	for _, team := range race.Teams() {
+		if team.NotStarted() || team.NotFinished() {
+			t.Errorf("team %d must be racing before any hand-over", team.ID)
+		}
+	}
+
+	input = `[10:03:00.000] 11 31 fall
+[10:05:00.000] 10 21
+[10:05:00.000] 12 21 22
+[10:05:10.000] 10 11
+[10:05:10.000] 12 11 12
+[10:09:00.000] 10 12
+[10:09:30.000] 11 22 broken ski`
+	applyAll(t, race, input)
+	// повторная передача, передача самому себе и чужому этапу не применяются
+	for _, line := range []string{
+		"[10:09:40.000] 12 11 12",
+		"[10:09:40.000] 12 11 11",
+		"[10:09:40.000] 12 21 41",
+	} {
+		ev, err := event.ParseLine(line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := race.Apply(ev); !errors.Is(err, ErrRejected) {
+			t.Errorf("%s: err = %v; want ErrRejected", line, err)
+		}
+	}
+	if c11, c41 := race.Competitors()[11], race.Competitors()[41]; c11.State != StateHandedOver ||
+		c41.ActualStart != mustParse(t, "10:00:00.000") {
+		t.Errorf("rejected hand-overs changed competitors: 11 %s, 41 started %v", c11.State, c41.ActualStart)
+	}
+	race.Finalize()
+
+	if len(race.Anomalies()) != 0 {
+		t.Errorf("unexpected anomalies %v", race.Anomalies())
+	}
+	c12 := race.Competitors()[12]
+	if c12.TeamId != 1 || c12.Leg != 2 || c12.ActualStart != mustParse(t, "10:05:10.000") || !c12.Finished {
+		t.Errorf("unexpected second leg %+v", c12)
+	}
+	teams := race.Teams()
+	if teams[0].ID != 1 || !teams[0].Finished() || teams[0].TotalTime() != mustParse(t, "00:09:00.000") {
+		t.Errorf("expected Norway to win in 9:00, got team %d finished=%v total=%v",
+			teams[0].ID, teams[0].Finished(), teams[0].TotalTime())
+	}
+	if teams[1].ID != 2 || !teams[1].NotFinished() {
+		t.Errorf("expected Sweden NotFinished, got %+v", teams[1])
+	}
+	// Финляндия сошла до передачи: второй этап не дисквалифицирован, а не начат
+	c32 := race.Competitors()[32]
+	if !c32.NotReached || c32.NotStarted || len(c32.OutgoingEvents) != 0 {
+		t.Errorf("expected leg 32 NotReached without event 32, got %+v", c32)
+	}
+	if teams[2].ID != 3 || !teams[2].NotFinished() || teams[3].ID != 4 || !teams[3].NotFinished() {
+		t.Errorf("expected Finland and Denmark NotFinished, got %d, %d", teams[2].ID, teams[3].ID)
+	}
+	if !strings.Contains(readLog(logger), "The competitor(21) handed over to competitor(22)") {
+		t.Error("log missing hand-over message")
+	}
+}
+
+func TestSpareRounds(t *testing.T) {
+	cfg := &config.Config{
+		Laps: 1, LapLen: 100, PenaltyLen: 150,
+		Start:  mustParse(t, "10:00:00.000"),
+		Format: config.FormatRelay,
+		Teams:  []config.Team{{ID: 1, Name: "Norway", Legs: []int{11}}},
+	}
+	logger := &MemoryLogger{}
+	race := NewRace(cfg, logger)
+
+	// 2 попадания основными патронами, 3 дополнительных — ещё 2, одна мишень остаётся
+	input := `[09:30:00.000] 1 11
+[10:00:00.000] 4 11
+[10:03:00.000] 5 11 1
+[10:03:01.000] 6 11 1
+[10:03:02.000] 6 11 2
+[10:03:10.000] 13 11
+[10:03:11.000] 6 11 3
+[10:03:15.000] 13 11
+[10:03:20.000] 13 11
+[10:03:21.000] 6 11 4
+[10:03:25.000] 13 11
+[10:03:30.000] 7 11`
+	applyAll(t, race, input)
+	c := race.Competitors()[11]
+
+	if c.Bouts[0].Spares != 3 || c.Spares != 3 {
+		t.Errorf("expected 3 spares used, got bout %d, total %d", c.Bouts[0].Spares, c.Spares)
+	}
+	if c.Hits != 4 || c.Misses() != 1 {
+		t.Errorf("expected 4 hits and 1 penalty loop, got %d hits, %d misses", c.Hits, c.Misses())
+	}
+	if !strings.Contains(readLog(logger), "The competitor(11) has no spare rounds left") {
+		t.Error("log missing spare limit message")
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	cfg := &config.Config{
+		Laps: 1, LapLen: 100, PenaltyLen: 150,
+		Start:      mustParse(t, "10:00:00.000"),
+		StartDelta: mustParse(t, "00:01:00.000"),
+	}
+	reg, err := registry.New([]registry.Athlete{{ID: 1, Name: "Johannes Boe", Nation: "NOR"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := &MemoryLogger{}
+	race := NewRace(cfg, logger)
+	race.SetRegistry(reg)
+
+	input := `[09:30:00.000] 1 1
+[09:30:01.000] 1 2`
+	applyAll(t, race, input)
+
+	log := readLog(logger)
+	if !strings.Contains(log, "The competitor(1: Johannes Boe, NOR) registered") {
+		t.Errorf("log missing athlete name:\n%s", log)
+	}
+	if !strings.Contains(log, "The competitor(2) is not in the athlete registry") {
+		t.Errorf("log missing unregistered warning:\n%s", log)
+	}
+	if a := race.Competitors()[1].Athlete; a == nil || a.Name != "Johannes Boe" {
+		t.Errorf("expected athlete attached to competitor 1, got %+v", a)
+	}
+}
+
+func TestCategoryLaps(t *testing.T) {
+	cfg := &config.Config{
+		Laps: 2, LapLen: 100, PenaltyLen: 150,
+		Format:     config.FormatMass,
+		Start:      mustParse(t, "10:00:00.000"),
+		Categories: map[string]config.Category{"Junior": {Laps: 1}},
+	}
+	reg, err := registry.New([]registry.Athlete{
+		{ID: 1, Category: "Senior"},
+		{ID: 2, Category: "Junior"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	race := NewRace(cfg, nil)
+	race.SetRegistry(reg)
+
+	input := `[09:30:00.000] 1 1
+[09:30:00.000] 1 2
+[10:00:00.000] 4 1
+[10:00:00.000] 4 2
+[10:05:00.000] 10 1
+[10:06:00.000] 10 2`
+	applyAll(t, race, input)
+	comps := race.Competitors()
+	if comps[1].Finished {
+		t.Error("senior finished after one of two laps")
+	}
+	if !comps[2].Finished {
+		t.Error("junior did not finish the one-lap course")
+	}
+
+	groups := RankingByCategory(cfg, comps)
+	if len(groups) != 2 || groups[0].Category != "Junior" || groups[1].Category != "Senior" {
+		t.Errorf("unexpected groups %+v", groups)
+	}
+}
+
+func TestPlacings(t *testing.T) {
+	cfg := &config.Config{Laps: 1}
+	lap := func(s string) []time.Duration { return []time.Duration{mustParse(t, s)} }
+	comps := map[int]*Competitor{
+		1: {ID: 1, Started: true, Finished: true, LapTimes: lap("00:10:00.000")},
+		2: {ID: 2, Started: true, Finished: true, LapTimes: lap("00:10:30.000")},
+		3: {ID: 3, Started: true, Finished: true, LapTimes: lap("00:10:00.000")},
+		4: {ID: 4, Started: true, Finished: true, LapTimes: lap("00:11:00.000")},
+		5: {ID: 5, Started: true, NotFinished: true},
+	}
+	ranked := Ranking(cfg, comps)
+	got := Placings(cfg, ranked)
+
+	want := []struct {
+		id, place   int
+		gap, behind string
+	}{
+		{1, 1, "00:00:00.000", "00:00:00.000"},
+		{3, 1, "00:00:00.000", "00:00:00.000"},
+		{2, 3, "00:00:30.000", "00:00:30.000"},
+		{4, 4, "00:01:00.000", "00:00:30.000"},
+		{5, 0, "00:00:00.000", "00:00:00.000"},
+	}
+	for i, w := range want {
+		p := got[i]
+		if ranked[i].ID != w.id || p.Place != w.place ||
+			p.Gap != mustParse(t, w.gap) || p.Behind != mustParse(t, w.behind) {
+			t.Errorf("row %d: competitor %d %+v; want %+v", i, ranked[i].ID, p, w)
+		}
+	}
+}
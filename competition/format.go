@@ -0,0 +1,222 @@
+package competition
+
+import (
+	"sort"
+	"time"
+
+	"github.com/ironywer/sunny_5_skiers/config"
+)
+
+// Format задаёт правила старта и ранжирования для вида гонки.
+type Format interface {
+	// Register вызывается при регистрации участника (событие 1).
+	Register(c *Competitor)
+	// StartDeadline — крайний срок старта; false, если опоздание не дисквалифицирует.
+	StartDeadline(c *Competitor) (time.Duration, bool)
+	// Result — время, по которому ранжируются финишировавшие: меньше — выше,
+	// равное — делёж места.
+	Result(c *Competitor) time.Duration
+}
+
+// FormatFor возвращает правила для cfg.Format.
+func FormatFor(cfg *config.Config) Format {
+	switch cfg.Format {
+	case config.FormatMass:
+		return massStart{cfg: cfg}
+	case config.FormatPursuit:
+		return pursuitStart{}
+	case config.FormatRelay:
+		return relayStart{cfg: cfg}
+	}
+	return intervalStart{cfg: cfg}
+}
+
+// intervalStart — раздельный старт: время старта разыгрывается (событие 2),
+// опоздание больше StartDelta дисквалифицирует, место — по итоговому времени.
+type intervalStart struct {
+	cfg *config.Config
+}
+
+func (intervalStart) Register(*Competitor) {}
+
+func (f intervalStart) StartDeadline(c *Competitor) (time.Duration, bool) {
+	return c.ScheduledAt + f.cfg.StartDelta, true
+}
+
+func (intervalStart) Result(c *Competitor) time.Duration {
+	return c.TotalTime()
+}
+
+// massStart — общий старт в cfg.Start: опоздавшие не дисквалифицируются,
+// место определяется порядком пересечения финиша с учётом штрафного времени.
+type massStart struct {
+	cfg *config.Config
+}
+
+func (f massStart) Register(c *Competitor) {
+	c.ScheduledAt = f.cfg.Start
+	c.State = StateScheduled
+}
+
+func (massStart) StartDeadline(*Competitor) (time.Duration, bool) {
+	return 0, false
+}
+
+func (massStart) Result(c *Competitor) time.Duration {
+	return c.FinishedAt + c.timePenalty()
+}
+
+// pursuitStart — гонка преследования: время старта каждого задано посевом
+// (событие 2 из стартового протокола), опоздание не дисквалифицирует,
+// место определяется порядком пересечения финиша с учётом штрафного времени.
+type pursuitStart struct{}
+
+func (pursuitStart) Register(*Competitor) {}
+
+func (pursuitStart) StartDeadline(*Competitor) (time.Duration, bool) {
+	return 0, false
+}
+
+func (pursuitStart) Result(c *Competitor) time.Duration {
+	return c.FinishedAt + c.timePenalty()
+}
+
+// Ranking упорядочивает участников: финишировавшие по правилам формата,
+// затем находящиеся на трассе по числу кругов и времени последней отметки,
+// затем ещё не стартовавшие, сошедшие и дисквалифицированные.
+func Ranking(cfg *config.Config, comps map[int]*Competitor) []*Competitor {
+	return rank(FormatFor(cfg), comps)
+}
+
+// Placing — место в протоколе и отставания. У не финишировавших Place == 0.
+type Placing struct {
+	Place  int
+	Result time.Duration // время, по которому определено место
+	Gap    time.Duration // отставание от лидера
+	Behind time.Duration // отставание от предыдущего финишировавшего
+}
+
+// Placings считает места участников, упорядоченных Ranking или
+// RankingByCategory: равный результат делит место, следующее место
+// пропускается (1, 1, 3).
+func Placings(cfg *config.Config, ranked []*Competitor) []Placing {
+	format := FormatFor(cfg)
+	return placings(len(ranked), func(i int) (time.Duration, bool) {
+		return format.Result(ranked[i]), standingGroup(ranked[i]) == groupFinished
+	})
+}
+
+// placings расставляет места по результатам result(i) уже упорядоченного
+// списка; finished == false — участник без места.
+func placings(n int, result func(i int) (time.Duration, bool)) []Placing {
+	out := make([]Placing, n)
+	var leader, prev *Placing
+	for i := range out {
+		res, finished := result(i)
+		if !finished {
+			continue
+		}
+		p := &out[i]
+		p.Result = res
+		p.Place = i + 1
+		if leader == nil {
+			leader = p
+		} else {
+			p.Gap = res - leader.Result
+			p.Behind = res - prev.Result
+			if res == prev.Result {
+				p.Place = prev.Place
+			}
+		}
+		prev = p
+	}
+	return out
+}
+
+// CategoryRanking — протокол одной категории в порядке мест.
+type CategoryRanking struct {
+	Category    string
+	Competitors []*Competitor
+}
+
+// RankingByCategory ранжирует каждую категорию отдельно. Категории идут
+// по алфавиту, участники без категории — последней группой.
+func RankingByCategory(cfg *config.Config, comps map[int]*Competitor) []CategoryRanking {
+	byCat := make(map[string]map[int]*Competitor)
+	for id, c := range comps {
+		cat := c.Category()
+		if byCat[cat] == nil {
+			byCat[cat] = make(map[int]*Competitor)
+		}
+		byCat[cat][id] = c
+	}
+	names := make([]string, 0, len(byCat))
+	for name := range byCat {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if (names[i] == "") != (names[j] == "") {
+			return names[j] == ""
+		}
+		return names[i] < names[j]
+	})
+
+	format := FormatFor(cfg)
+	out := make([]CategoryRanking, len(names))
+	for i, name := range names {
+		out[i] = CategoryRanking{Category: name, Competitors: rank(format, byCat[name])}
+	}
+	return out
+}
+
+func rank(format Format, comps map[int]*Competitor) []*Competitor {
+	out := make([]*Competitor, 0, len(comps))
+	for _, c := range comps {
+		out = append(out, c)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		ga, gb := standingGroup(a), standingGroup(b)
+		if ga != gb {
+			return ga < gb
+		}
+		switch ga {
+		case groupFinished:
+			if ra, rb := format.Result(a), format.Result(b); ra != rb {
+				return ra < rb
+			}
+		case groupRacing:
+			if a.LapsDone != b.LapsDone {
+				return a.LapsDone > b.LapsDone
+			}
+			ea, eb := a.CurrentLapAt-a.ScheduledAt, b.CurrentLapAt-b.ScheduledAt
+			if ea != eb {
+				return ea < eb
+			}
+		}
+		return a.ID < b.ID
+	})
+	return out
+}
+
+const (
+	groupFinished = iota
+	groupRacing
+	groupWaiting
+	groupNotFinished
+	groupNotStarted
+)
+
+func standingGroup(c *Competitor) int {
+	switch {
+	case c.NotStarted:
+		return groupNotStarted
+	case c.NotFinished:
+		return groupNotFinished
+	case c.Finished:
+		return groupFinished
+	case c.Started:
+		return groupRacing
+	}
+	return groupWaiting
+}
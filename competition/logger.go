@@ -0,0 +1,101 @@
+package competition
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ironywer/sunny_5_skiers/config"
+)
+
+// EventLogger принимает сообщения журнала гонки. Время события передаётся
+// отдельно, форматирование строки остаётся за реализацией.
+type EventLogger interface {
+	Log(at time.Duration, msg string)
+}
+
+// WriterLogger пишет строки вида "[HH:MM:SS.sss] msg" в io.Writer.
+type WriterLogger struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewWriterLogger(w io.Writer) *WriterLogger {
+	return &WriterLogger{w: w}
+}
+
+func (l *WriterLogger) Log(at time.Duration, msg string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	fmt.Fprintf(l.w, "[%s] %s\n", config.FormatClock(at), msg)
+}
+
+// FileLogger — WriterLogger поверх файла, который создаётся заново.
+type FileLogger struct {
+	*WriterLogger
+	f *os.File
+}
+
+func NewFileLogger(path string) (*FileLogger, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o666)
+	if err != nil {
+		return nil, err
+	}
+	return &FileLogger{WriterLogger: NewWriterLogger(f), f: f}, nil
+}
+
+func (l *FileLogger) Close() error {
+	return l.f.Close()
+}
+
+type LogEntry struct {
+	Time    time.Duration
+	Message string
+}
+
+// MemoryLogger накапливает записи в памяти, удобен в тестах.
+type MemoryLogger struct {
+	mu      sync.Mutex
+	entries []LogEntry
+}
+
+func (l *MemoryLogger) Log(at time.Duration, msg string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, LogEntry{Time: at, Message: msg})
+}
+
+func (l *MemoryLogger) Entries() []LogEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]LogEntry(nil), l.entries...)
+}
+
+// Lines возвращает записи в том же виде, в каком их пишет WriterLogger.
+func (l *MemoryLogger) Lines() []string {
+	entries := l.Entries()
+	lines := make([]string, len(entries))
+	for i, e := range entries {
+		lines[i] = fmt.Sprintf("[%s] %s", config.FormatClock(e.Time), e.Message)
+	}
+	return lines
+}
+
+// MultiLogger рассылает каждую запись всем переданным журналам.
+func MultiLogger(loggers ...EventLogger) EventLogger {
+	return multiLogger(loggers)
+}
+
+type multiLogger []EventLogger
+
+func (m multiLogger) Log(at time.Duration, msg string) {
+	for _, l := range m {
+		l.Log(at, msg)
+	}
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Log(time.Duration, string) {}
@@ -0,0 +1,86 @@
+package competition
+
+import (
+	"math"
+	"time"
+
+	"github.com/ironywer/sunny_5_skiers/config"
+)
+
+// PenaltyVisit — один заход на штрафные круги (события 8..9).
+type PenaltyVisit struct {
+	EnteredAt time.Duration
+	LeftAt    time.Duration
+	Loops     int // число кругов, выведенное из времени и PenaltyLen; без PenaltyLoopSpeed — 1
+}
+
+func (v PenaltyVisit) Duration() time.Duration {
+	return v.LeftAt - v.EnteredAt
+}
+
+// PenaltyCheck сравнивает пройденные штрафные круги с числом промахов.
+type PenaltyCheck struct {
+	Misses      int
+	Loops       int
+	Unskied     int           // промахов больше, чем пройденных кругов
+	Extra       int           // кругов больше, чем промахов
+	TimePenalty time.Duration // UnskiedLoopPenalty за каждый непройденный круг
+}
+
+func (p PenaltyCheck) Compliant() bool {
+	return p.Unskied == 0 && p.Extra == 0
+}
+
+// loopsKnown — число кругов за визит выводится из времени, а не принимается за один.
+func loopsKnown(cfg *config.Config) bool {
+	return cfg.PenaltyLoopSpeed > 0 && cfg.PenaltyLen > 0
+}
+
+// inferLoops выводит число кругов за визит: время визита на скорости
+// PenaltyLoopSpeed, делённое на длину круга, но не меньше одного.
+func inferLoops(cfg *config.Config, d time.Duration) int {
+	if !loopsKnown(cfg) {
+		return 1
+	}
+	loops := int(math.Round(d.Seconds() * cfg.PenaltyLoopSpeed / float64(cfg.PenaltyLen)))
+	return max(loops, 1)
+}
+
+func (c *Competitor) enterPenalty(at time.Duration) {
+	c.PenaltyVisits = append(c.PenaltyVisits, PenaltyVisit{EnteredAt: at})
+}
+
+func (c *Competitor) leavePenalty(cfg *config.Config, at time.Duration) {
+	if len(c.PenaltyVisits) == 0 {
+		return
+	}
+	v := &c.PenaltyVisits[len(c.PenaltyVisits)-1]
+	v.LeftAt = at
+	v.Loops = inferLoops(cfg, v.Duration())
+}
+
+// PenaltyLoops — общее число пройденных штрафных кругов.
+func (c *Competitor) PenaltyLoops() int {
+	var n int
+	for _, v := range c.PenaltyVisits {
+		n += v.Loops
+	}
+	return n
+}
+
+// checkPenalties сравнивает круги с промахами; false, если число кругов
+// неизвестно: заходы были, а PenaltyLoopSpeed не задана.
+func (c *Competitor) checkPenalties(cfg *config.Config) (PenaltyCheck, bool) {
+	if len(c.PenaltyVisits) > 0 && !loopsKnown(cfg) {
+		return PenaltyCheck{}, false
+	}
+	p := PenaltyCheck{Misses: c.Misses(), Loops: c.PenaltyLoops()}
+	if p.Misses > p.Loops {
+		p.Unskied = p.Misses - p.Loops
+		p.TimePenalty = time.Duration(p.Unskied) * cfg.UnskiedLoopPenalty
+	} else {
+		p.Extra = p.Loops - p.Misses
+	}
+	c.PenaltyCheck = &p
+	return p, true
+}
@@ -0,0 +1,458 @@
+package competition
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ironywer/sunny_5_skiers/config"
+	"github.com/ironywer/sunny_5_skiers/event"
+	"github.com/ironywer/sunny_5_skiers/registry"
+)
+
+// Race хранит состояние участников и принимает события по одному,
+// поэтому одинаково подходит и для файла, и для живой трансляции.
+type Race struct {
+	cfg         *config.Config
+	format      Format
+	logger      EventLogger
+	output      EventSink
+	outErr      error
+	competitors map[int]*Competitor
+	legs        map[int]legRef
+	registry    *registry.Registry
+	anomalies   []Anomaly
+	finalized   bool
+	now         time.Duration // время последнего применённого события
+}
+
+var (
+	// ErrRejected — событие отклонено политикой аномалий reject.
+	ErrRejected = errors.New("событие нарушает последовательность")
+	// ErrFinalized — событие пришло после Finalize.
+	ErrFinalized = errors.New("гонка уже завершена")
+	// ErrOutOfOrder — событие раньше уже применённого.
+	ErrOutOfOrder = errors.New("событие раньше предыдущего")
+)
+
+// EventSink получает объединённый поток входящих и сгенерированных событий.
+type EventSink interface {
+	WriteEvent(ev event.Event) error
+}
+
+// NewRace создаёт гонку; журнал событий пишется в logger (nil — никуда).
+func NewRace(cfg *config.Config, logger EventLogger) *Race {
+	if logger == nil {
+		logger = nopLogger{}
+	}
+	return &Race{
+		cfg:         cfg,
+		format:      FormatFor(cfg),
+		logger:      logger,
+		competitors: make(map[int]*Competitor),
+		legs:        legIndex(cfg),
+	}
+}
+
+// SetOutput направляет поток событий в sink: каждое входящее событие
+// и следом сгенерированные им исходящие (32, 33). Поток упорядочен по
+// времени: дисквалификация не вышедшего на старт пишется перед первым
+// событием позже его крайнего срока, оставшиеся — в Finalize.
+func (r *Race) SetOutput(sink EventSink) {
+	r.output = sink
+}
+
+// Err возвращает первую ошибку записи в поток событий.
+func (r *Race) Err() error {
+	return r.outErr
+}
+
+// SetRegistry подключает реестр участников: их данные попадают в журнал
+// и в Competitor.Athlete, а события незарегистрированных ID отмечаются в журнале.
+func (r *Race) SetRegistry(reg *registry.Registry) {
+	r.registry = reg
+}
+
+// Anomalies возвращает события, нарушившие последовательность
+// (при политике ignore список пуст).
+func (r *Race) Anomalies() []Anomaly {
+	return r.anomalies
+}
+
+// Competitors возвращает участников по их ID.
+func (r *Race) Competitors() map[int]*Competitor {
+	return r.competitors
+}
+
+// Apply применяет одно входящее событие. События должны поступать по времени:
+// событие раньше предыдущего, после Finalize или с неразборчивым временем
+// старта не применяется и возвращает ошибку. Событие, невозможное в текущем
+// состоянии участника, обрабатывается согласно cfg.AnomalyPolicy; при reject
+// возвращается ошибка, оборачивающая ErrRejected, а гонка не меняется.
+func (r *Race) Apply(ev event.Event) error {
+	cfg := r.cfg
+	if r.finalized {
+		return fmt.Errorf("%s: %w", ev, ErrFinalized)
+	}
+	if ev.Fixtime < r.now {
+		return fmt.Errorf("%s: %w (%s)", ev, ErrOutOfOrder, config.FormatClock(r.now))
+	}
+	var start time.Duration
+	if ev.EventId == 2 {
+		d, err := config.ParseRowForDuration(ev.ExtraParams)
+		if err != nil {
+			return fmt.Errorf("%s: время старта: %w", ev, err)
+		}
+		start = d
+	}
+	var receiver int
+	if ev.EventId == 12 {
+		id, err := r.checkHandOver(ev)
+		if err != nil {
+			return err
+		}
+		receiver = id
+	}
+	if err := r.checkSequence(ev, r.stateAt(ev.CompetitorId, ev.Fixtime)); err != nil {
+		return err
+	}
+	r.now = ev.Fixtime
+	r.expire(ev.Fixtime)
+
+	c := r.competitor(ev.CompetitorId, ev.Fixtime)
+	r.write(ev)
+
+	switch ev.EventId {
+	case 1: // регистрация
+		c.RegisteredAt = ev.Fixtime
+		c.State = StateRegistered
+		r.format.Register(c)
+		r.logf(ev.Fixtime, "The competitor(%s) registered", r.name(ev.CompetitorId))
+	case 2: // время старта
+		r.logf(ev.Fixtime, "The start time for the competitor(%s) was set by a draw to %s",
+			r.name(ev.CompetitorId), config.FormatClock(start))
+		c.ScheduledAt = start
+		c.State = StateScheduled
+
+	case 3: // на стартовой линии
+		c.State = StateOnStartLine
+		r.logf(ev.Fixtime, "The competitor(%s) is on the start line", r.name(ev.CompetitorId))
+	case 4: // выход на трассу
+		if c.NotStarted {
+			break // уже дисквалифицирован по истечении крайнего срока
+		}
+		c.ActualStart = ev.Fixtime
+		c.Started = true
+
+		if deadline, ok := r.format.StartDeadline(c); ok && ev.Fixtime > deadline {
+			c.NotStarted = true
+			c.State = StateDisqualified
+			r.emit(c, ev.Fixtime, 32)
+			r.logf(ev.Fixtime, "The competitor(%s) was disqualified", r.name(ev.CompetitorId))
+		} else {
+			c.CurrentLapAt = ev.Fixtime
+			c.State = StateOnTrack
+			r.logf(ev.Fixtime, "The competitor(%s) has started", r.name(ev.CompetitorId))
+		}
+
+	case 5: // вход на стрельбище
+		c.enterRange(ev.Fixtime, ev.ExtraParams, cfg.BoutTargets())
+		c.State = StateOnRange
+		r.logf(ev.Fixtime, "The competitor(%s) is on the firing range", r.name(ev.CompetitorId))
+	case 6: // попадание
+		targetId := ev.ExtraParams
+		if !c.hitTarget(targetId) {
+			r.logf(ev.Fixtime, "The target(%s) hit by competitor(%s) is invalid and was not counted",
+				targetId, r.name(ev.CompetitorId))
+			break
+		}
+		r.logf(ev.Fixtime, "The target(%s) has been hit by competitor(%s)",
+			targetId, r.name(ev.CompetitorId))
+	case 7: // уход с стрельбища
+		c.leaveRange(ev.Fixtime)
+		if cfg.TimeScoring() {
+			if b := c.currentBout(); b != nil {
+				c.MissPenalty += time.Duration(b.Misses()) * cfg.PenaltyPerMiss()
+			}
+		}
+		c.State = StateOnTrack
+		r.logf(ev.Fixtime, "The competitor(%s) left the firing range", r.name(ev.CompetitorId))
+
+	case 8: // заход на штрафные круги
+		c.PenaltyCount++
+		c.PenaltyStartedAt = ev.Fixtime
+		c.enterPenalty(ev.Fixtime)
+		c.State = StateInPenalty
+		r.logf(ev.Fixtime, "The competitor(%s) entered the penalty laps", r.name(ev.CompetitorId))
+	case 9: // выход со штрафных кругов
+		c.State = StateOnTrack
+		c.PenaltyTime += ev.Fixtime - c.PenaltyStartedAt
+		c.leavePenalty(cfg, ev.Fixtime)
+		r.logf(ev.Fixtime, "The competitor(%s) left the penalty laps", r.name(ev.CompetitorId))
+	case 10: // конец основного круга
+		lap := ev.Fixtime - c.CurrentLapAt
+		c.LapTimes = append(c.LapTimes, lap)
+		c.CurrentLapAt = ev.Fixtime
+		c.LapsDone++
+		r.logf(ev.Fixtime, "The competitor(%s) ended the main lap", r.name(ev.CompetitorId))
+		for _, is := range c.checkFiringLines(c.LapsDone, cfg.FiringLines, ev.Fixtime) {
+			switch is.Kind {
+			case IssueSkippedBout:
+				r.logf(ev.Fixtime, "The competitor(%s) skipped the firing range on lap(%d)",
+					r.name(ev.CompetitorId), is.Lap)
+			case IssueUnknownRange:
+				r.logf(ev.Fixtime, "The competitor(%s) shot on an unknown firing range(%d) on lap(%d)",
+					r.name(ev.CompetitorId), is.Range, is.Lap)
+			default:
+				r.logf(ev.Fixtime, "The competitor(%s) made an extra bout on the firing range(%d) on lap(%d)",
+					r.name(ev.CompetitorId), is.Range, is.Lap)
+			}
+		}
+		if c.LapsDone == cfg.ForCategory(c.Category()).Laps {
+			c.Finished = true
+			c.State = StateFinished
+			c.FinishedAt = ev.Fixtime
+			r.emit(c, ev.Fixtime, 33)
+			r.logf(ev.Fixtime, "The competitor(%s) has finished", r.name(ev.CompetitorId))
+			if !cfg.TimeScoring() {
+				if p, ok := c.checkPenalties(cfg); ok && !p.Compliant() {
+					r.logf(ev.Fixtime, "The competitor(%s) skied %d penalty laps for %d misses",
+						r.name(ev.CompetitorId), p.Loops, p.Misses)
+				}
+			}
+		}
+
+	case 12: // передача эстафеты
+		n := r.competitor(receiver, ev.Fixtime)
+		n.ScheduledAt = ev.Fixtime
+		n.ActualStart = ev.Fixtime
+		n.CurrentLapAt = ev.Fixtime
+		n.Started = true
+		n.State = StateOnTrack
+		c.State = StateHandedOver
+		r.logf(ev.Fixtime, "The competitor(%s) handed over to competitor(%s)",
+			r.name(ev.CompetitorId), r.name(receiver))
+
+	case 13: // дозарядка дополнительного патрона
+		if c.loadSpare(cfg.SparesPerBout()) {
+			r.logf(ev.Fixtime, "The competitor(%s) loaded a spare round", r.name(ev.CompetitorId))
+		} else {
+			r.logf(ev.Fixtime, "The competitor(%s) has no spare rounds left", r.name(ev.CompetitorId))
+		}
+
+	case 11: // не может продолжить
+		c.NotFinished = true
+		c.State = StateNotFinished
+		c.ActualStart = ev.Fixtime
+		r.logf(ev.Fixtime, "The competitor(%s) can`t continue: %s",
+			r.name(ev.CompetitorId), ev.ExtraParams)
+	}
+	return nil
+}
+
+// checkHandOver проверяет передачу эстафеты и возвращает ID получателя:
+// это должен быть ещё не стартовавший участник следующего этапа команды.
+// Неверная передача отклоняется при любой политике аномалий.
+func (r *Race) checkHandOver(ev event.Event) (int, error) {
+	next, err := strconv.Atoi(ev.ExtraParams)
+	if err != nil {
+		r.logf(ev.Fixtime, "The competitor(%s) made an invalid hand-over: %s, rejected",
+			r.name(ev.CompetitorId), ev.ExtraParams)
+		return 0, fmt.Errorf("%s: %w", ev, ErrRejected)
+	}
+	var want int
+	if c, ok := r.competitors[ev.CompetitorId]; ok {
+		want = nextLeg(r.cfg, c)
+	}
+	if next != want {
+		r.logf(ev.Fixtime, "The hand-over of competitor(%s) to competitor(%s) is not to the next leg, rejected",
+			r.name(ev.CompetitorId), r.name(next))
+		return 0, fmt.Errorf("%s: %w", ev, ErrRejected)
+	}
+	if n, ok := r.competitors[next]; ok && n.Started {
+		r.logf(ev.Fixtime, "The competitor(%s) has already started, hand-over rejected", r.name(next))
+		return 0, fmt.Errorf("%s: %w", ev, ErrRejected)
+	}
+	return next, nil
+}
+
+// stateAt — состояние участника к моменту at: с истёкшим крайним сроком
+// старта он уже дисквалифицирован. Ни участника, ни гонку не меняет.
+func (r *Race) stateAt(id int, at time.Duration) State {
+	c, ok := r.competitors[id]
+	if !ok {
+		return StateNew
+	}
+	if c.State == StateScheduled || c.State == StateOnStartLine {
+		if deadline, ok := r.format.StartDeadline(c); ok && at > deadline {
+			return StateDisqualified
+		}
+	}
+	return c.State
+}
+
+// checkSequence сверяет событие с состоянием участника и применяет
+// политику для аномалий. Возвращает ошибку, если событие отклонено.
+func (r *Race) checkSequence(ev event.Event, state State) error {
+	if r.cfg.AnomalyPolicy == config.PolicyIgnore {
+		return nil
+	}
+	expected, ok := checkTransition(state, ev.EventId)
+	if ok {
+		return nil
+	}
+	a := Anomaly{
+		Time:         ev.Fixtime,
+		CompetitorId: ev.CompetitorId,
+		EventId:      ev.EventId,
+		State:        state,
+		Expected:     expected,
+	}
+	r.anomalies = append(r.anomalies, a)
+	if r.cfg.AnomalyPolicy == config.PolicyReject {
+		r.logf(ev.Fixtime, "%s, rejected", a)
+		return fmt.Errorf("%s: %w", a, ErrRejected)
+	}
+	r.logf(ev.Fixtime, "%s", a)
+	return nil
+}
+
+// emit фиксирует исходящее событие участника и пишет его в поток.
+func (r *Race) emit(c *Competitor, at time.Duration, eventId int) {
+	c.OutgoingEvents = append(c.OutgoingEvents, OutgoingEvent{Time: at, EventId: eventId})
+	r.write(event.Event{Fixtime: at, EventId: eventId, CompetitorId: c.ID})
+}
+
+func (r *Race) write(ev event.Event) {
+	if r.output == nil || r.outErr != nil {
+		return
+	}
+	r.outErr = r.output.WriteEvent(ev)
+}
+
+func (r *Race) competitor(id int, at time.Duration) *Competitor {
+	c, ok := r.competitors[id]
+	if !ok {
+		c = &Competitor{ID: id}
+		if ref, ok := r.legs[id]; ok {
+			c.TeamId, c.Leg = ref.team, ref.leg
+		}
+		r.competitors[id] = c
+		if r.registry != nil {
+			if a, ok := r.registry.Get(id); ok {
+				c.Athlete = a
+			} else {
+				r.logf(at, "The competitor(%d) is not in the athlete registry", id)
+			}
+		}
+	}
+	return c
+}
+
+// name — подпись участника в журнале: ID и, если есть в реестре, имя и страна.
+func (r *Race) name(id int) string {
+	if c, ok := r.competitors[id]; ok && c.Athlete != nil {
+		return fmt.Sprintf("%d: %s", id, c.Athlete.Label())
+	}
+	return strconv.Itoa(id)
+}
+
+// Teams возвращает команды эстафеты в порядке TeamRanking.
+func (r *Race) Teams() []*Team {
+	return TeamRanking(r.cfg, r.competitors)
+}
+
+func (r *Race) logf(at time.Duration, format string, args ...any) {
+	r.logger.Log(at, fmt.Sprintf(format, args...))
+}
+
+// expire дисквалифицирует участников с разыгранным стартом, чей крайний
+// срок истёк раньше at, чтобы событие 32 попало в поток вовремя.
+func (r *Race) expire(at time.Duration) {
+	r.disqualify(func(c *Competitor) (time.Duration, bool) {
+		if c.State != StateScheduled && c.State != StateOnStartLine {
+			return 0, false
+		}
+		deadline, ok := r.format.StartDeadline(c)
+		return deadline, ok && at > deadline
+	})
+}
+
+// Finalize закрывает гонку: всех, кто так и не стартовал, помечает NotStarted,
+// а оставшихся на трассе — NotFinished. После Finalize Apply не принимает
+// события; повторный вызов ничего не делает.
+func (r *Race) Finalize() {
+	if r.finalized {
+		return
+	}
+	r.finalized = true
+	// этапы эстафеты, до которых не дошла передача, не дисквалифицируются
+	for _, c := range r.competitors {
+		if c.Leg > 1 && !c.Started {
+			c.NotReached = true
+		}
+	}
+	r.disqualify(func(c *Competitor) (time.Duration, bool) {
+		deadline, ok := r.format.StartDeadline(c)
+		if !ok {
+			deadline = c.ScheduledAt
+		}
+		return deadline, true
+	})
+
+	var racing []*Competitor
+	for _, c := range r.competitors {
+		if c.Started && !c.NotStarted && !c.NotFinished && !c.Finished {
+			racing = append(racing, c)
+		}
+	}
+	sort.Slice(racing, func(i, j int) bool { return racing[i].ID < racing[j].ID })
+	for _, c := range racing {
+		// как при событии 11: сход в момент последнего события гонки
+		c.NotFinished = true
+		c.State = StateNotFinished
+		c.ActualStart = r.now
+		r.logf(r.now, "The competitor(%s) did not finish", r.name(c.ID))
+	}
+}
+
+// disqualify помечает NotStarted не стартовавших участников, для которых
+// due возвращает true, и пишет их события 32 по времени крайнего срока.
+func (r *Race) disqualify(due func(c *Competitor) (time.Duration, bool)) {
+	var pending []*Competitor
+	for _, c := range r.competitors {
+		if c.Started || c.NotStarted || c.NotReached {
+			continue
+		}
+		deadline, ok := due(c)
+		if !ok {
+			continue
+		}
+		c.NotStarted = true
+		c.ActualStart = deadline
+		c.State = StateDisqualified
+		pending = append(pending, c)
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		if pending[i].ActualStart != pending[j].ActualStart {
+			return pending[i].ActualStart < pending[j].ActualStart
+		}
+		return pending[i].ID < pending[j].ID
+	})
+	for _, c := range pending {
+		r.emit(c, c.ActualStart, 32)
+		r.logf(c.ActualStart, "The competitor(%s) was disqualified", r.name(c.ID))
+	}
+}
+
+// Finalized сообщает, вызывался ли Finalize.
+func (r *Race) Finalized() bool {
+	return r.finalized
+}
+
+// Standings возвращает текущую таблицу в порядке Ranking.
+func (r *Race) Standings() []*Competitor {
+	return rank(r.format, r.competitors)
+}
@@ -0,0 +1,186 @@
+package competition
+
+import (
+	"sort"
+	"time"
+
+	"github.com/ironywer/sunny_5_skiers/config"
+)
+
+// Team — команда эстафеты; Legs идут в порядке этапов.
+type Team struct {
+	ID   int
+	Name string
+	Legs []*Competitor
+}
+
+// TotalTime — сумма времени пройденных этапов.
+func (t *Team) TotalTime() time.Duration {
+	var tot time.Duration
+	for _, c := range t.Legs {
+		if c.Started && !c.NotStarted {
+			tot += c.TotalTime()
+		}
+	}
+	return tot
+}
+
+// Finished — финишировал участник последнего этапа.
+func (t *Team) Finished() bool {
+	return len(t.Legs) > 0 && t.Legs[len(t.Legs)-1].Finished
+}
+
+// FinishedAt — время пересечения финиша участником последнего этапа.
+func (t *Team) FinishedAt() time.Duration {
+	if !t.Finished() {
+		return 0
+	}
+	return t.Legs[len(t.Legs)-1].FinishedAt
+}
+
+// NotStarted — участник первого этапа не вышел на старт.
+func (t *Team) NotStarted() bool {
+	return len(t.Legs) == 0 || t.Legs[0].NotStarted
+}
+
+// NotFinished — команда сошла: один из этапов не завершён или до него
+// не дошла передача.
+func (t *Team) NotFinished() bool {
+	if t.NotStarted() {
+		return false
+	}
+	for _, c := range t.Legs {
+		if c.NotFinished || c.NotStarted || c.NotReached {
+			return true
+		}
+	}
+	return false
+}
+
+// legsDone — число завершённых этапов.
+func (t *Team) legsDone() int {
+	var n int
+	for _, c := range t.Legs {
+		if c.Finished {
+			n++
+		}
+	}
+	return n
+}
+
+type legRef struct {
+	team int
+	leg  int // с 1
+}
+
+func legIndex(cfg *config.Config) map[int]legRef {
+	idx := make(map[int]legRef)
+	for _, t := range cfg.Teams {
+		for i, id := range t.Legs {
+			idx[id] = legRef{team: t.ID, leg: i + 1}
+		}
+	}
+	return idx
+}
+
+// nextLeg возвращает ID участника следующего этапа или 0.
+func nextLeg(cfg *config.Config, c *Competitor) int {
+	for _, t := range cfg.Teams {
+		if t.ID == c.TeamId && c.Leg < len(t.Legs) {
+			return t.Legs[c.Leg]
+		}
+	}
+	return 0
+}
+
+// relayStart — эстафета: первый этап уходит общим стартом в cfg.Start,
+// остальные стартуют в момент передачи (событие 12). Места команд —
+// по порядку финиша последнего этапа.
+type relayStart struct {
+	cfg *config.Config
+}
+
+func (f relayStart) Register(c *Competitor) {
+	c.ScheduledAt = f.cfg.Start
+	if c.Leg <= 1 {
+		c.State = StateScheduled
+	}
+}
+
+func (relayStart) StartDeadline(*Competitor) (time.Duration, bool) {
+	return 0, false
+}
+
+func (relayStart) Result(c *Competitor) time.Duration {
+	return c.FinishedAt
+}
+
+// TeamPlacings считает места команд, упорядоченных TeamRanking, по времени
+// финиша последнего этапа.
+func TeamPlacings(teams []*Team) []Placing {
+	return placings(len(teams), func(i int) (time.Duration, bool) {
+		return teams[i].FinishedAt(), teamGroup(teams[i]) == groupFinished
+	})
+}
+
+// TeamRanking собирает команды из cfg.Teams и упорядочивает их: финишировавшие
+// по времени финиша, затем находящиеся на трассе по числу пройденных этапов,
+// затем сошедшие и не стартовавшие. Этап без событий ещё ждёт передачи,
+// а не начатый этап после сошедшего отмечается NotReached.
+func TeamRanking(cfg *config.Config, comps map[int]*Competitor) []*Team {
+	teams := make([]*Team, 0, len(cfg.Teams))
+	for _, ct := range cfg.Teams {
+		t := &Team{ID: ct.ID, Name: ct.Name}
+		for i, id := range ct.Legs {
+			c, ok := comps[id]
+			if !ok {
+				c = &Competitor{ID: id, TeamId: ct.ID, Leg: i + 1}
+			}
+			if i > 0 && !c.Started && !c.NotReached && retired(t.Legs[i-1]) {
+				cp := *c
+				cp.NotReached = true
+				c = &cp
+			}
+			t.Legs = append(t.Legs, c)
+		}
+		teams = append(teams, t)
+	}
+	sort.SliceStable(teams, func(i, j int) bool {
+		a, b := teams[i], teams[j]
+		ga, gb := teamGroup(a), teamGroup(b)
+		if ga != gb {
+			return ga < gb
+		}
+		switch ga {
+		case groupFinished:
+			if a.FinishedAt() != b.FinishedAt() {
+				return a.FinishedAt() < b.FinishedAt()
+			}
+		case groupRacing:
+			if a.legsDone() != b.legsDone() {
+				return a.legsDone() > b.legsDone()
+			}
+		}
+		return a.ID < b.ID
+	})
+	return teams
+}
+
+// retired — после этого этапа передачи уже не будет.
+func retired(c *Competitor) bool {
+	return c.NotStarted || c.NotFinished || c.NotReached
+}
+
+func teamGroup(t *Team) int {
+	switch {
+	case t.NotStarted():
+		return groupNotStarted
+	case t.NotFinished():
+		return groupNotFinished
+	case t.Finished():
+		return groupFinished
+	case t.Legs[0].Started:
+		return groupRacing
+	}
+	return groupWaiting
+}
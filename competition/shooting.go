@@ -0,0 +1,138 @@
+package competition
+
+import (
+	"strconv"
+	"time"
+)
+
+// Bout — одно посещение огневого рубежа (события 5..7).
+type Bout struct {
+	Range     int // номер рубежа из события 5
+	Lap       int // круг, на котором была стрельба, с 1
+	EnteredAt time.Duration
+	LeftAt    time.Duration
+	Shots     int   // число мишеней на рубеже
+	Spares    int   // дозаряженные дополнительные патроны (событие 13)
+	Targets   []int // поражённые мишени из события 6
+}
+
+// Hits — число поражённых мишеней; повторное попадание в ту же мишень не считается.
+func (b Bout) Hits() int {
+	seen := make(map[int]bool, len(b.Targets))
+	for _, t := range b.Targets {
+		seen[t] = true
+	}
+	return len(seen)
+}
+
+// Misses — мишени, оставшиеся непоражёнными после всех выстрелов,
+// включая дополнительные; за каждую полагается штрафной круг.
+func (b Bout) Misses() int {
+	if m := b.Shots - b.Hits(); m > 0 {
+		return m
+	}
+	return 0
+}
+
+// Misses — общее число промахов по всем рубежам.
+func (c *Competitor) Misses() int {
+	var n int
+	for _, b := range c.Bouts {
+		n += b.Misses()
+	}
+	return n
+}
+
+// currentBout возвращает рубеж, на котором участник сейчас стреляет.
+func (c *Competitor) currentBout() *Bout {
+	if c.State != StateOnRange || len(c.Bouts) == 0 {
+		return nil
+	}
+	return &c.Bouts[len(c.Bouts)-1]
+}
+
+func (c *Competitor) enterRange(at time.Duration, param string, targets int) {
+	rng, _ := strconv.Atoi(param)
+	c.Bouts = append(c.Bouts, Bout{
+		Range:     rng,
+		Lap:       c.LapsDone + 1,
+		EnteredAt: at,
+		Shots:     targets,
+	})
+	c.Shots += targets
+}
+
+// hitTarget засчитывает попадание; false, если номер мишени не число
+// или вне 1..Shots текущего рубежа — такое попадание не засчитывается.
+func (c *Competitor) hitTarget(param string) bool {
+	b := c.currentBout()
+	if b == nil {
+		return true
+	}
+	target, err := strconv.Atoi(param)
+	if err != nil || target < 1 || target > b.Shots {
+		return false
+	}
+	before := b.Hits()
+	b.Targets = append(b.Targets, target)
+	c.Hits += b.Hits() - before
+	return true
+}
+
+// loadSpare дозаряжает дополнительный патрон; false, если лимит рубежа исчерпан.
+func (c *Competitor) loadSpare(limit int) bool {
+	b := c.currentBout()
+	if b == nil || b.Spares >= limit {
+		return false
+	}
+	b.Spares++
+	c.Spares++
+	return true
+}
+
+func (c *Competitor) leaveRange(at time.Duration) {
+	if b := c.currentBout(); b != nil {
+		b.LeftAt = at
+	}
+}
+
+const (
+	IssueSkippedBout  = "skipped"
+	IssueExtraBout    = "extra"
+	IssueUnknownRange = "unknown"
+)
+
+// CourseIssue — расхождение стрельбы на одном круге с config.FiringLines.
+type CourseIssue struct {
+	Time  time.Duration // время окончания круга
+	Lap   int
+	Kind  string // IssueSkippedBout, IssueExtraBout или IssueUnknownRange
+	Range int    // номер рубежа; 0 для пропуска или если в событии 5 он не указан
+}
+
+// checkFiringLines проверяет стрельбу на круге lap: участник стреляет один
+// раз за круг на одном из рубежей 1..firingLines.
+func (c *Competitor) checkFiringLines(lap, firingLines int, at time.Duration) []CourseIssue {
+	if firingLines <= 0 {
+		return nil
+	}
+	var issues []CourseIssue
+	var bouts int
+	for _, b := range c.Bouts {
+		if b.Lap != lap {
+			continue
+		}
+		bouts++
+		switch {
+		case b.Range < 1 || b.Range > firingLines:
+			issues = append(issues, CourseIssue{Time: at, Lap: lap, Kind: IssueUnknownRange, Range: b.Range})
+		case bouts > 1:
+			issues = append(issues, CourseIssue{Time: at, Lap: lap, Kind: IssueExtraBout, Range: b.Range})
+		}
+	}
+	if bouts == 0 {
+		issues = append(issues, CourseIssue{Time: at, Lap: lap, Kind: IssueSkippedBout})
+	}
+	c.CourseIssues = append(c.CourseIssues, issues...)
+	return issues
+}
@@ -0,0 +1,99 @@
+package competition
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// State — положение участника в гонке, которое определяет,
+// какие входящие события для него допустимы.
+type State int
+
+const (
+	StateNew State = iota
+	StateRegistered
+	StateScheduled
+	StateOnStartLine
+	StateOnTrack
+	StateOnRange
+	StateInPenalty
+	StateFinished
+	StateHandedOver // этап эстафеты закончен и передан следующему
+	StateNotFinished
+	StateDisqualified
+)
+
+var stateNames = [...]string{
+	StateNew:          "New",
+	StateRegistered:   "Registered",
+	StateScheduled:    "Scheduled",
+	StateOnStartLine:  "OnStartLine",
+	StateOnTrack:      "OnTrack",
+	StateOnRange:      "OnRange",
+	StateInPenalty:    "InPenalty",
+	StateFinished:     "Finished",
+	StateHandedOver:   "HandedOver",
+	StateNotFinished:  "NotFinished",
+	StateDisqualified: "Disqualified",
+}
+
+func (s State) String() string {
+	if s >= 0 && int(s) < len(stateNames) {
+		return stateNames[s]
+	}
+	return fmt.Sprintf("State(%d)", int(s))
+}
+
+// allowedFrom — из каких состояний допустимо каждое входящее событие.
+var allowedFrom = map[int][]State{
+	1:  {StateNew},
+	2:  {StateRegistered, StateScheduled},
+	3:  {StateScheduled},
+	4:  {StateScheduled, StateOnStartLine},
+	5:  {StateOnTrack},
+	6:  {StateOnRange},
+	7:  {StateOnRange},
+	8:  {StateOnTrack},
+	9:  {StateInPenalty},
+	10: {StateOnTrack},
+	11: {StateRegistered, StateScheduled, StateOnStartLine, StateOnTrack, StateOnRange, StateInPenalty},
+	12: {StateFinished},
+	13: {StateOnRange},
+}
+
+// Anomaly — событие, невозможное в текущем состоянии участника.
+type Anomaly struct {
+	Time         time.Duration
+	CompetitorId int
+	EventId      int
+	State        State
+	Expected     []State // пусто для неизвестного EventId
+}
+
+func (a Anomaly) String() string {
+	if len(a.Expected) == 0 {
+		return fmt.Sprintf("The event(%d) of competitor(%d) is unknown", a.EventId, a.CompetitorId)
+	}
+	expected := make([]string, len(a.Expected))
+	for i, s := range a.Expected {
+		expected[i] = s.String()
+	}
+	return fmt.Sprintf("The event(%d) of competitor(%d) is out of sequence: state %s, expected %s",
+		a.EventId, a.CompetitorId, a.State, strings.Join(expected, "|"))
+}
+
+// checkTransition сообщает, допустимо ли событие eventId в состоянии s,
+// и возвращает список допустимых для него состояний.
+func checkTransition(s State, eventId int) ([]State, bool) {
+	from, ok := allowedFrom[eventId]
+	if !ok {
+		return nil, false
+	}
+	for _, f := range from {
+		if f == s {
+			return from, true
+		}
+	}
+	return from, false
+}
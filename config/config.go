@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 	"time"
 )
 
@@ -14,16 +15,145 @@ type Config struct {
 	FiringLines int `json:"firingLines"`
 	Start       time.Duration
 	StartDelta  time.Duration
+	// AnomalyPolicy — реакция на событие, невозможное в текущем состоянии
+	// участника: ignore, warn (по умолчанию) или reject.
+	AnomalyPolicy string `json:"anomalyPolicy"`
+	// TargetsPerBout — число мишеней (выстрелов) на одном огневом рубеже.
+	TargetsPerBout int `json:"targetsPerBout"`
+	// PenaltyLoopSpeed — ожидаемая скорость на штрафном круге (м/с), по ней
+	// из времени визита выводится число пройденных кругов. 0 — один круг за визит.
+	PenaltyLoopSpeed float64 `json:"penaltyLoopSpeed"`
+	// UnskiedLoopPenalty — штраф ко времени за каждый не пройденный штрафной круг.
+	UnskiedLoopPenalty time.Duration
+	// Scoring — как наказывается промах: loop (штрафной круг, по умолчанию)
+	// или time (индивидуальная гонка, MissPenalty за каждый промах).
+	Scoring     string `json:"scoring"`
+	MissPenalty time.Duration
+	// Format — вид гонки: interval (раздельный старт, по умолчанию), mass,
+	// pursuit (гонка преследования со стартом по отставанию) или relay.
+	Format string `json:"format"`
+	// SpareRounds — дополнительные патроны на рубеже, которые досылаются
+	// вручную (событие 13) до назначения штрафных кругов.
+	SpareRounds *int `json:"spareRounds"`
+	// Athletes — путь к реестру участников (JSON или CSV); относительный
+	// путь отсчитывается от каталога файла конфигурации.
+	Athletes string `json:"athletes"`
+	// Teams — команды эстафеты с участниками в порядке этапов.
+	Teams []Team `json:"teams"`
+	// Categories — дистанции категорий, которые бегут меньше (или больше)
+	// кругов основной дистанции; ключ — категория из реестра участников.
+	Categories map[string]Category `json:"categories"`
 }
 
+// Category переопределяет дистанцию для категории; нулевые поля берутся
+// из основной конфигурации.
+type Category struct {
+	Laps   int `json:"laps"`
+	LapLen int `json:"lapLen"`
+}
+
+// ForCategory возвращает конфигурацию с дистанцией категории name;
+// если для категории ничего не задано, возвращает саму c.
+func (c *Config) ForCategory(name string) *Config {
+	cat, ok := c.Categories[name]
+	if !ok {
+		return c
+	}
+	cc := *c
+	if cat.Laps > 0 {
+		cc.Laps = cat.Laps
+	}
+	if cat.LapLen > 0 {
+		cc.LapLen = cat.LapLen
+	}
+	return &cc
+}
+
+type Team struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Legs []int  `json:"legs"`
+}
+
+const (
+	FormatInterval = "interval"
+	FormatMass     = "mass"
+	FormatPursuit  = "pursuit"
+	FormatRelay    = "relay"
+)
+
+const (
+	ScoringLoop = "loop"
+	ScoringTime = "time"
+)
+
+// DefaultRelaySpareRounds — число дополнительных патронов на рубеже в эстафете.
+const DefaultRelaySpareRounds = 3
+
+// SparesPerBout возвращает число дополнительных патронов на рубеже:
+// spareRounds, если задан, иначе 3 для эстафеты и 0 для остальных гонок.
+func (c *Config) SparesPerBout() int {
+	if c.SpareRounds != nil {
+		return *c.SpareRounds
+	}
+	if c.Format == FormatRelay {
+		return DefaultRelaySpareRounds
+	}
+	return 0
+}
+
+// DefaultMissPenalty — штраф за промах в индивидуальной гонке.
+const DefaultMissPenalty = time.Minute
+
+// TimeScoring сообщает, начисляется ли за промахи штрафное время вместо кругов.
+func (c *Config) TimeScoring() bool {
+	return c.Scoring == ScoringTime
+}
+
+// PenaltyPerMiss возвращает штраф за промах с учётом значения по умолчанию.
+func (c *Config) PenaltyPerMiss() time.Duration {
+	if c.MissPenalty > 0 {
+		return c.MissPenalty
+	}
+	return DefaultMissPenalty
+}
+
+// DefaultTargetsPerBout используется, если targetsPerBout не задан.
+const DefaultTargetsPerBout = 5
+
+// BoutTargets возвращает число мишеней на рубеже с учётом значения по умолчанию.
+func (c *Config) BoutTargets() int {
+	if c.TargetsPerBout > 0 {
+		return c.TargetsPerBout
+	}
+	return DefaultTargetsPerBout
+}
+
+const (
+	PolicyIgnore = "ignore"
+	PolicyWarn   = "warn"
+	PolicyReject = "reject"
+)
+
 func LoadConfig(filePath string) (*Config, error) {
 	type tmp_Config struct {
-		Laps        int    `json:"laps"`
-		LapLen      int    `json:"lapLen"`
-		PenaltyLen  int    `json:"penaltyLen"`
-		FiringLines int    `json:"firingLines"`
-		Start       string `json:"start"`
-		StartDelta  string `json:"startDelta"`
+		Laps               int                 `json:"laps"`
+		LapLen             int                 `json:"lapLen"`
+		PenaltyLen         int                 `json:"penaltyLen"`
+		FiringLines        int                 `json:"firingLines"`
+		Start              string              `json:"start"`
+		StartDelta         string              `json:"startDelta"`
+		AnomalyPolicy      string              `json:"anomalyPolicy"`
+		TargetsPerBout     int                 `json:"targetsPerBout"`
+		PenaltyLoopSpeed   float64             `json:"penaltyLoopSpeed"`
+		UnskiedLoopPenalty string              `json:"unskiedLoopPenalty"`
+		Scoring            string              `json:"scoring"`
+		MissPenalty        string              `json:"missPenalty"`
+		Format             string              `json:"format"`
+		SpareRounds        *int                `json:"spareRounds"`
+		Athletes           string              `json:"athletes"`
+		Teams              []Team              `json:"teams"`
+		Categories         map[string]Category `json:"categories"`
 	}
 	data, err := os.ReadFile(filePath)
 	if err != nil {
@@ -44,16 +174,87 @@ func LoadConfig(filePath string) (*Config, error) {
 	if err != nil {
 		return nil, err
 	}
+	unskiedLoopPenalty, err := parseOptionalDuration(tmp_config.UnskiedLoopPenalty)
+	if err != nil {
+		return nil, err
+	}
+	missPenalty, err := parseOptionalDuration(tmp_config.MissPenalty)
+	if err != nil {
+		return nil, err
+	}
+	switch tmp_config.Scoring {
+	case "", ScoringLoop, ScoringTime:
+	default:
+		return nil, fmt.Errorf("неизвестный режим scoring: %s", tmp_config.Scoring)
+	}
+	switch tmp_config.Format {
+	case "", FormatInterval, FormatMass, FormatPursuit, FormatRelay:
+	default:
+		return nil, fmt.Errorf("неизвестный вид гонки format: %s", tmp_config.Format)
+	}
+	athletes := tmp_config.Athletes
+	if athletes != "" && !filepath.IsAbs(athletes) {
+		athletes = filepath.Join(filepath.Dir(filePath), athletes)
+	}
+	if err := checkTeams(tmp_config.Teams); err != nil {
+		return nil, err
+	}
+	for name, cat := range tmp_config.Categories {
+		if cat.Laps < 0 || cat.LapLen < 0 {
+			return nil, fmt.Errorf("категория %s: отрицательная дистанция", name)
+		}
+	}
+	switch tmp_config.AnomalyPolicy {
+	case "", PolicyIgnore, PolicyWarn, PolicyReject:
+	default:
+		return nil, fmt.Errorf("неизвестная политика anomalyPolicy: %s", tmp_config.AnomalyPolicy)
+	}
 	return &Config{
-		Laps:        tmp_config.Laps,
-		LapLen:      tmp_config.LapLen,
-		PenaltyLen:  tmp_config.PenaltyLen,
-		FiringLines: tmp_config.FiringLines,
-		Start:       start,
-		StartDelta:  startDelta,
+		Laps:               tmp_config.Laps,
+		LapLen:             tmp_config.LapLen,
+		PenaltyLen:         tmp_config.PenaltyLen,
+		FiringLines:        tmp_config.FiringLines,
+		Start:              start,
+		StartDelta:         startDelta,
+		AnomalyPolicy:      tmp_config.AnomalyPolicy,
+		TargetsPerBout:     tmp_config.TargetsPerBout,
+		PenaltyLoopSpeed:   tmp_config.PenaltyLoopSpeed,
+		UnskiedLoopPenalty: unskiedLoopPenalty,
+		Scoring:            tmp_config.Scoring,
+		MissPenalty:        missPenalty,
+		Format:             tmp_config.Format,
+		SpareRounds:        tmp_config.SpareRounds,
+		Athletes:           athletes,
+		Teams:              tmp_config.Teams,
+		Categories:         tmp_config.Categories,
 	}, nil
 }
 
+// checkTeams проверяет, что в каждой команде есть этапы и каждый участник
+// бежит не больше одного этапа.
+func checkTeams(teams []Team) error {
+	seen := make(map[int]int)
+	for _, t := range teams {
+		if len(t.Legs) == 0 {
+			return fmt.Errorf("в команде %d нет этапов", t.ID)
+		}
+		for _, id := range t.Legs {
+			if other, ok := seen[id]; ok {
+				return fmt.Errorf("участник %d указан в командах %d и %d", id, other, t.ID)
+			}
+			seen[id] = t.ID
+		}
+	}
+	return nil
+}
+
+func parseOptionalDuration(row string) (time.Duration, error) {
+	if row == "" {
+		return 0, nil
+	}
+	return ParseRowForDuration(row)
+}
+
 func ParseRowForDuration(row string) (time.Duration, error) {
 	t, err := time.Parse("15:04:05.000", row)
 	if err != nil {
@@ -42,6 +42,44 @@ func TestLoadConfig(t *testing.T) {
 	}
 }
 
+func TestLoadConfig_BadAnomalyPolicy(t *testing.T) {
+	json_data := `{"laps": 1, "start": "10:00:00", "startDelta": "00:01:30", "anomalyPolicy": "explode"}`
+	test_json := filepath.Join(t.TempDir(), "bad_policy.json")
+	if err := os.WriteFile(test_json, []byte(json_data), 0644); err != nil {
+		t.Fatalf("Ошибка создания tmp-файла: %v", err)
+	}
+
+	if _, err := LoadConfig(test_json); err == nil {
+		t.Error("ожидалась ошибка для неизвестной anomalyPolicy")
+	}
+}
+
+func TestLoadConfig_DuplicateTeamMember(t *testing.T) {
+	json_data := `{"laps": 1, "start": "10:00:00", "startDelta": "00:01:30", "format": "relay",
+		"teams": [{"id": 1, "name": "A", "legs": [1, 2]}, {"id": 2, "name": "B", "legs": [3, 2]}]}`
+	test_json := filepath.Join(t.TempDir(), "relay.json")
+	if err := os.WriteFile(test_json, []byte(json_data), 0644); err != nil {
+		t.Fatalf("Ошибка создания tmp-файла: %v", err)
+	}
+
+	if _, err := LoadConfig(test_json); err == nil {
+		t.Error("ожидалась ошибка для участника в двух командах")
+	}
+}
+
+func TestLoadConfig_EmptyTeam(t *testing.T) {
+	json_data := `{"laps": 1, "start": "10:00:00", "startDelta": "00:01:30", "format": "relay",
+		"teams": [{"id": 1, "name": "A", "legs": [1, 2]}, {"id": 2, "name": "B", "legs": []}]}`
+	test_json := filepath.Join(t.TempDir(), "relay.json")
+	if err := os.WriteFile(test_json, []byte(json_data), 0644); err != nil {
+		t.Fatalf("Ошибка создания tmp-файла: %v", err)
+	}
+
+	if _, err := LoadConfig(test_json); err == nil {
+		t.Error("ожидалась ошибка для команды без этапов")
+	}
+}
+
 func TestParseRowForDuration(t *testing.T) {
 	tests := []struct {
 		row      string
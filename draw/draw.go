@@ -0,0 +1,127 @@
+package draw
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ironywer/sunny_5_skiers/config"
+	"github.com/ironywer/sunny_5_skiers/event"
+)
+
+// Entry — строка стартового протокола раздельного старта.
+type Entry struct {
+	Order        int // стартовый номер по порядку, с 1
+	CompetitorId int
+	StartAt      time.Duration
+	Group        int // номер группы посева, с 1; последняя — участники вне групп
+}
+
+// Event возвращает событие 2 строки протокола, помеченное временем жеребьёвки at.
+func (e Entry) Event(at time.Duration) event.Event {
+	return event.Event{Fixtime: at, EventId: 2, CompetitorId: e.CompetitorId, ExtraParams: config.FormatClock(e.StartAt)}
+}
+
+// Options — параметры жеребьёвки.
+type Options struct {
+	Start time.Duration // старт первого участника
+	Delta time.Duration // интервал между стартами
+	// Groups — группы посева в порядке старта. Внутри группы порядок
+	// разыгрывается; участники, не попавшие ни в одну группу, образуют
+	// последнюю группу.
+	Groups [][]int
+	// Seed делает жеребьёвку воспроизводимой: при одном и том же seed
+	// и наборе участников получается тот же протокол.
+	Seed uint64
+}
+
+// StartList разыгрывает порядок старта участников ids. Повторы ID
+// и ID из групп, которых нет среди участников, пропускаются.
+func StartList(ids []int, opts Options) []Entry {
+	registered := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		registered[id] = true
+	}
+
+	drawn := make(map[int]bool, len(ids))
+	var groups [][]int
+	for _, g := range opts.Groups {
+		var group []int
+		for _, id := range g {
+			if registered[id] && !drawn[id] {
+				drawn[id] = true
+				group = append(group, id)
+			}
+		}
+		groups = append(groups, group)
+	}
+	var rest []int
+	for _, id := range ids {
+		if !drawn[id] {
+			drawn[id] = true
+			rest = append(rest, id)
+		}
+	}
+	groups = append(groups, rest)
+
+	rng := rand.New(rand.NewPCG(opts.Seed, 0))
+	var entries []Entry
+	for gi, group := range groups {
+		// порядок внутри группы не должен зависеть от порядка регистрации
+		slices.Sort(group)
+		rng.Shuffle(len(group), func(i, j int) {
+			group[i], group[j] = group[j], group[i]
+		})
+		for _, id := range group {
+			n := len(entries)
+			entries = append(entries, Entry{
+				Order:        n + 1,
+				CompetitorId: id,
+				StartAt:      opts.Start + time.Duration(n)*opts.Delta,
+				Group:        gi + 1,
+			})
+		}
+	}
+	return entries
+}
+
+// ParseGroups разбирает группы посева вида "1,2,3;4,5": группы
+// разделяются точкой с запятой, ID внутри группы — запятой.
+func ParseGroups(s string) ([][]int, error) {
+	var groups [][]int
+	for _, g := range strings.Split(s, ";") {
+		var group []int
+		for _, f := range strings.Split(g, ",") {
+			f = strings.TrimSpace(f)
+			if f == "" {
+				continue
+			}
+			id, err := strconv.Atoi(f)
+			if err != nil {
+				return nil, fmt.Errorf("неверный ID участника в группе посева: %q", f)
+			}
+			group = append(group, id)
+		}
+		if len(group) > 0 {
+			groups = append(groups, group)
+		}
+	}
+	return groups, nil
+}
+
+// Registered возвращает участников, зарегистрированных событием 1,
+// в порядке регистрации.
+func Registered(events []event.Event) []int {
+	seen := make(map[int]bool)
+	var ids []int
+	for _, ev := range events {
+		if ev.EventId == 1 && !seen[ev.CompetitorId] {
+			seen[ev.CompetitorId] = true
+			ids = append(ids, ev.CompetitorId)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,66 @@
+package draw_test
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ironywer/sunny_5_skiers/draw"
+	"github.com/ironywer/sunny_5_skiers/event"
+)
+
+func TestStartList(t *testing.T) {
+	t.Parallel()
+
+	events := []event.Event{
+		{EventId: 1, CompetitorId: 5}, {EventId: 1, CompetitorId: 2}, {EventId: 1, CompetitorId: 9},
+		{EventId: 1, CompetitorId: 7}, {EventId: 1, CompetitorId: 2}, {EventId: 3, CompetitorId: 4},
+		{EventId: 1, CompetitorId: 1},
+	}
+	ids := draw.Registered(events)
+	if !reflect.DeepEqual(ids, []int{5, 2, 9, 7, 1}) {
+		t.Fatalf("Registered = %v", ids)
+	}
+
+	groups, err := draw.ParseGroups("9, 7; 42")
+	if err != nil {
+		t.Fatalf("ParseGroups: %v", err)
+	}
+	opts := draw.Options{
+		Start:  10 * time.Hour,
+		Delta:  30 * time.Second,
+		Groups: groups,
+		Seed:   7,
+	}
+	entries := draw.StartList(ids, opts)
+	if len(entries) != 5 {
+		t.Fatalf("expected 5 entries, got %+v", entries)
+	}
+	for i, e := range entries {
+		if e.Order != i+1 || e.StartAt != opts.Start+time.Duration(i)*opts.Delta {
+			t.Errorf("entry %d = %+v", i, e)
+		}
+		wantGroup := 3 // ID 42 не зарегистрирован, вторая группа пуста
+		if i < 2 {
+			wantGroup = 1
+		}
+		if e.Group != wantGroup {
+			t.Errorf("entry %d group = %d; want %d", i, e.Group, wantGroup)
+		}
+	}
+
+	// тот же seed при другом порядке регистрации даёт тот же протокол
+	again := draw.StartList([]int{1, 2, 5, 7, 9}, opts)
+	if !reflect.DeepEqual(entries, again) {
+		t.Errorf("draw is not reproducible:\n%+v\n%+v", entries, again)
+	}
+
+	if got := entries[0].Event(9*time.Hour + 30*time.Minute).String(); got != "[09:30:00.000] 2 "+strconv.Itoa(entries[0].CompetitorId)+" 10:00:00.000" {
+		t.Errorf("draw event = %q", got)
+	}
+
+	if _, err := draw.ParseGroups("1,x"); err == nil {
+		t.Error("expected error for bad group id")
+	}
+}
@@ -2,6 +2,10 @@ package event
 
 import (
 	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
 	"os"
 	"strconv"
 	"strings"
@@ -17,42 +21,205 @@ type Event struct {
 	ExtraParams  string
 }
 
+// String возвращает событие в формате входного файла.
+func (e Event) String() string {
+	s := fmt.Sprintf("[%s] %d %d", config.FormatClock(e.Fixtime), e.EventId, e.CompetitorId)
+	if e.ExtraParams != "" {
+		s += " " + e.ExtraParams
+	}
+	return s
+}
+
+// Writer пишет события построчно в формате входного файла.
+type Writer struct {
+	w io.Writer
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+func (w *Writer) WriteEvent(ev Event) error {
+	_, err := fmt.Fprintln(w.w, ev.String())
+	return err
+}
+
+// ParseError описывает строку файла событий, которую не удалось разобрать.
+type ParseError struct {
+	File  string // имя файла, если известно
+	Line  int    // номер строки, начиная с 1
+	Field string // поле: time, eventId, competitorId или line
+	Text  string // исходный текст поля (или всей строки)
+	Err   error
+}
+
+func (e *ParseError) Error() string {
+	pos := e.File
+	if e.Line > 0 {
+		pos = fmt.Sprintf("%s:%d", pos, e.Line)
+	}
+	if pos != "" {
+		pos += ": "
+	}
+	return fmt.Sprintf("%sполе %s %q: %v", pos, e.Field, e.Text, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorList — отчёт об ошибочных строках, собранный в мягком режиме.
+type ErrorList []*ParseError
+
+func (l ErrorList) Error() string {
+	switch len(l) {
+	case 0:
+		return "нет ошибок"
+	case 1:
+		return l[0].Error()
+	}
+	msgs := make([]string, len(l))
+	for i, e := range l {
+		msgs[i] = e.Error()
+	}
+	return fmt.Sprintf("%d ошибочных строк:\n%s", len(l), strings.Join(msgs, "\n"))
+}
+
+// Reader читает события построчно из произвольного источника,
+// не загружая весь поток в память.
+type Reader struct {
+	// File подставляется в ParseError.
+	File string
+	// Lenient включает мягкий режим: ошибочные строки пропускаются
+	// и накапливаются в Errors вместо прерывания чтения.
+	Lenient bool
+
+	scanner *bufio.Scanner
+	line    int
+	errs    ErrorList
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{scanner: bufio.NewScanner(r)}
+}
+
+// Read возвращает следующее событие; по окончании потока — io.EOF.
+// Пустые строки пропускаются.
+func (r *Reader) Read() (Event, error) {
+	for r.scanner.Scan() {
+		r.line++
+		text := r.scanner.Text()
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		ev, err := ParseLine(text)
+		if err == nil {
+			return ev, nil
+		}
+		var pe *ParseError
+		if errors.As(err, &pe) {
+			pe.File = r.File
+			pe.Line = r.line
+		}
+		if !r.Lenient || pe == nil {
+			return Event{}, err
+		}
+		r.errs = append(r.errs, pe)
+	}
+	if err := r.scanner.Err(); err != nil {
+		return Event{}, err
+	}
+	return Event{}, io.EOF
+}
+
+// Errors возвращает строки, пропущенные в мягком режиме.
+func (r *Reader) Errors() ErrorList {
+	return r.errs
+}
+
+// All перебирает события до конца потока или до первой ошибки.
+func (r *Reader) All() iter.Seq2[Event, error] {
+	return func(yield func(Event, error) bool) {
+		for {
+			ev, err := r.Read()
+			if err == io.EOF {
+				return
+			}
+			if !yield(ev, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// ParseLine разбирает строку формата "[HH:MM:SS.sss] eventID competitorID [extraParams]".
+// Ошибки возвращаются как *ParseError без номера строки.
+func ParseLine(line string) (Event, error) {
+	var event Event
+	parts := strings.SplitN(line, " ", 4)
+	if len(parts) < 3 {
+		return Event{}, &ParseError{Field: "line", Text: line, Err: errTooFewFields}
+	}
+	tmp := parts[0]
+	if len(tmp) < 2 || tmp[0] != '[' || tmp[len(tmp)-1] != ']' {
+		return Event{}, &ParseError{Field: "time", Text: tmp, Err: errNoBrackets}
+	}
+	var err error
+	event.Fixtime, err = config.ParseRowForDuration(tmp[1 : len(tmp)-1])
+	if err != nil {
+		return Event{}, &ParseError{Field: "time", Text: tmp, Err: err}
+	}
+	event.EventId, err = strconv.Atoi(parts[1])
+	if err != nil {
+		return Event{}, &ParseError{Field: "eventId", Text: parts[1], Err: err}
+	}
+	event.CompetitorId, err = strconv.Atoi(parts[2])
+	if err != nil {
+		return Event{}, &ParseError{Field: "competitorId", Text: parts[2], Err: err}
+	}
+	if len(parts) == 4 {
+		event.ExtraParams = parts[3]
+	} else {
+		event.ExtraParams = ""
+	}
+	return event, nil
+}
+
+var (
+	errTooFewFields = errors.New("ожидается [время] eventID competitorID [extraParams]")
+	errNoBrackets   = errors.New("время должно быть в квадратных скобках")
+)
+
 func LoadEvents(filePath string) ([]Event, error) {
+	events, _, err := loadEvents(filePath, false)
+	return events, err
+}
+
+// LoadEventsLenient читает файл целиком, пропуская ошибочные строки.
+// Список пропущенных строк возвращается вторым значением.
+func LoadEventsLenient(filePath string) ([]Event, ErrorList, error) {
+	return loadEvents(filePath, true)
+}
+
+func loadEvents(filePath string, lenient bool) ([]Event, ErrorList, error) {
 
 	file, err := os.Open(filePath)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer file.Close()
 
-	var events = make([]Event, 0)
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-		var event Event
-		var tmp string
-		parts := strings.SplitN(line, " ", 4)
-		if len(parts) < 3 {
-			return nil, os.ErrInvalid
-		}
-		tmp = parts[0]
-		event.EventId, _ = strconv.Atoi(parts[1])
-		event.CompetitorId, _ = strconv.Atoi(parts[2])
-		if len(parts) == 4 {
-			event.ExtraParams = parts[3]
-		} else {
-			event.ExtraParams = ""
-		}
+	r := NewReader(file)
+	r.File = filePath
+	r.Lenient = lenient
 
-		event.Fixtime, err = config.ParseRowForDuration(tmp[1 : len(tmp)-1])
+	var events = make([]Event, 0)
+	for ev, err := range r.All() {
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
-		events = append(events, event)
-	}
-	if err := scanner.Err(); err != nil {
-		return nil, err
+		events = append(events, ev)
 	}
 
-	return events, nil
+	return events, r.Errors(), nil
 }
@@ -1,9 +1,12 @@
 package event_test
 
 import (
+	"errors"
+	"io"
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -109,3 +112,126 @@ func TestLoadEvents_InvalidTime(t *testing.T) {
 		t.Error("expected error for invalid time, got nil")
 	}
 }
+
+func TestReader_Stream(t *testing.T) {
+	t.Parallel()
+
+	r := event.NewReader(strings.NewReader("[09:15:00.841] 2 1 09:30:00.000\n[09:29:45.734] 3 1\n"))
+
+	ev, err := r.Read()
+	if err != nil {
+		t.Fatalf("first Read returned error: %v", err)
+	}
+	if ev.EventId != 2 || ev.ExtraParams != "09:30:00.000" {
+		t.Errorf("first event = %+v", ev)
+	}
+	ev, err = r.Read()
+	if err != nil {
+		t.Fatalf("second Read returned error: %v", err)
+	}
+	if ev.EventId != 3 || ev.Fixtime != mustParse(t, "09:29:45.734") {
+		t.Errorf("second event = %+v", ev)
+	}
+	if _, err := r.Read(); err != io.EOF {
+		t.Errorf("expected io.EOF at end of stream, got %v", err)
+	}
+}
+
+func TestReader_AllStopsOnError(t *testing.T) {
+	t.Parallel()
+
+	r := event.NewReader(strings.NewReader("[09:15:00.841] 3 1\nbad\n[09:29:45.734] 3 2\n"))
+
+	var n int
+	var gotErr error
+	for _, err := range r.All() {
+		if err != nil {
+			gotErr = err
+			continue
+		}
+		n++
+	}
+	if n != 1 || gotErr == nil {
+		t.Errorf("got %d events and err %v; want 1 event and an error", n, gotErr)
+	}
+}
+
+func TestParseError_Fields(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		input string
+		line  int
+		field string
+		text  string
+	}{
+		{"too few fields", "[09:15:00.841] 2\n", 1, "line", "[09:15:00.841] 2"},
+		{"no brackets", "[09:15:00.841] 3 1\n09:15:00.841 2 1\n", 2, "time", "09:15:00.841"},
+		{"bad event id", "[09:15:00.841] x 1\n", 1, "eventId", "x"},
+		{"bad competitor id", "\n[09:15:00.841] 2 y\n", 2, "competitorId", "y"},
+	}
+
+	for _, tt := range tests {
+		r := event.NewReader(strings.NewReader(tt.input))
+		r.File = "events.txt"
+
+		var err error
+		for _, err = range r.All() {
+		}
+		var pe *event.ParseError
+		if !errors.As(err, &pe) {
+			t.Errorf("%s: expected *ParseError, got %v", tt.name, err)
+			continue
+		}
+		if pe.File != "events.txt" || pe.Line != tt.line || pe.Field != tt.field || pe.Text != tt.text {
+			t.Errorf("%s: got %+v", tt.name, pe)
+		}
+	}
+}
+
+func TestLoadEventsLenient(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "mixed.txt")
+	content := `[09:15:00.841] 2 1 09:30:00.000
+garbage
+[09:29:45.734] 3 1
+[bad] 4 1
+[09:30:01.005] 4 1`
+	if err := os.WriteFile(filePath, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write temp file: %v", err)
+	}
+
+	evs, bad, err := event.LoadEventsLenient(filePath)
+	if err != nil {
+		t.Fatalf("LoadEventsLenient returned error: %v", err)
+	}
+	if len(evs) != 3 {
+		t.Errorf("expected 3 events, got %d", len(evs))
+	}
+	if len(bad) != 2 || bad[0].Line != 2 || bad[1].Line != 4 {
+		t.Errorf("unexpected error report: %v", bad)
+	}
+}
+
+func TestWriter_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	input := "[09:15:00.841] 2 1 09:30:00.000\n[09:59:03.872] 11 1 Lost in the forest\n[10:00:00.000] 33 1\n"
+
+	var buf strings.Builder
+	w := event.NewWriter(&buf)
+	for ev, err := range event.NewReader(strings.NewReader(input)).All() {
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		if err := w.WriteEvent(ev); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	if buf.String() != input {
+		t.Errorf("round trip = %q; want %q", buf.String(), input)
+	}
+}
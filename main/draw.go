@@ -0,0 +1,135 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/ironywer/sunny_5_skiers/config"
+	"github.com/ironywer/sunny_5_skiers/draw"
+	"github.com/ironywer/sunny_5_skiers/event"
+	"github.com/ironywer/sunny_5_skiers/registry"
+)
+
+// runDraw разыгрывает стартовый протокол раздельного старта и печатает
+// события 2. Участники берутся из событий 1 файла событий, а если он
+// не указан — из реестра участников конфигурации.
+func runDraw(args []string) {
+	fs := flag.NewFlagSet("draw", flag.ExitOnError)
+	drawAt := fs.String("at", "",
+		"время событий жеребьёвки (по умолчанию за 30 минут до start)")
+	seedStr := fs.String("seed", "",
+		"seed жеребьёвки; без него выбирается случайный и печатается в stderr")
+	groupsStr := fs.String("groups", "",
+		`группы посева в порядке старта, например "1,2,3;4,5"`)
+	listPath := fs.String("list", "", "файл для стартового протокола")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s draw [-seed N] [-groups 1,2;3] [-at HH:MM:SS] [-list file] <config.json> [events.txt]\n",
+			filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	cfg, err := config.LoadConfig(fs.Arg(0))
+	if err != nil {
+		log.Fatalf("Error loading config: %v", err)
+	}
+
+	at := drawTime(cfg, *drawAt)
+
+	opts := draw.Options{Start: cfg.Start, Delta: cfg.StartDelta}
+	if *seedStr != "" {
+		opts.Seed, err = strconv.ParseUint(*seedStr, 10, 64)
+		if err != nil {
+			log.Fatalf("Error parsing -seed: %v", err)
+		}
+	} else {
+		opts.Seed = rand.Uint64()
+		fmt.Fprintf(os.Stderr, "seed: %d\n", opts.Seed)
+	}
+	if opts.Groups, err = draw.ParseGroups(*groupsStr); err != nil {
+		log.Fatalf("Error parsing -groups: %v", err)
+	}
+
+	var reg *registry.Registry
+	if cfg.Athletes != "" {
+		reg, err = registry.Load(cfg.Athletes)
+		if err != nil {
+			log.Fatalf("Error loading athletes: %v", err)
+		}
+	}
+	var ids []int
+	if fs.NArg() == 2 {
+		events, err := event.LoadEvents(fs.Arg(1))
+		if err != nil {
+			log.Fatalf("Error loading events: %v", err)
+		}
+		ids = draw.Registered(events)
+	} else if reg != nil {
+		ids = reg.IDs()
+	} else {
+		log.Fatalf("No competitors: pass an events file or set athletes in the config")
+	}
+
+	entries := draw.StartList(ids, opts)
+	if *listPath != "" {
+		writeStartList(*listPath, entries, reg)
+	}
+	evs := make([]event.Event, len(entries))
+	for i, e := range entries {
+		evs[i] = e.Event(at)
+	}
+	writeDraws(evs)
+}
+
+// drawTime — время событий жеребьёвки из флага -at, по умолчанию за 30 минут до start.
+func drawTime(cfg *config.Config, at string) time.Duration {
+	if at == "" {
+		if cfg.Start < 30*time.Minute {
+			log.Fatalf("Start %s is earlier than 00:30:00: set the draw time with -at", config.FormatClock(cfg.Start))
+		}
+		return cfg.Start - 30*time.Minute
+	}
+	d, err := config.ParseRowForDuration(at)
+	if err != nil {
+		log.Fatalf("Error parsing -at: %v", err)
+	}
+	return d
+}
+
+// writeDraws печатает события 2 стартового протокола в stdout.
+func writeDraws(evs []event.Event) {
+	w := event.NewWriter(os.Stdout)
+	for _, ev := range evs {
+		if err := w.WriteEvent(ev); err != nil {
+			log.Fatalf("Error writing start list: %v", err)
+		}
+	}
+}
+
+// writeStartList сохраняет протокол: "номер время ID [данные участника]".
+func writeStartList(path string, entries []draw.Entry, reg *registry.Registry) {
+	f, err := os.Create(path)
+	if err != nil {
+		log.Fatalf("Error opening %s: %v", path, err)
+	}
+	defer f.Close()
+	for _, e := range entries {
+		line := fmt.Sprintf("%d %s %d", e.Order, config.FormatClock(e.StartAt), e.CompetitorId)
+		if a, ok := reg.Get(e.CompetitorId); ok {
+			line += " " + a.Details()
+		}
+		if _, err := fmt.Fprintln(f, line); err != nil {
+			log.Fatalf("Error writing %s: %v", path, err)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"os"
@@ -9,39 +11,142 @@ import (
 	"github.com/ironywer/sunny_5_skiers/competition"
 	"github.com/ironywer/sunny_5_skiers/config"
 	"github.com/ironywer/sunny_5_skiers/event"
+	"github.com/ironywer/sunny_5_skiers/registry"
 	"github.com/ironywer/sunny_5_skiers/report"
 )
 
 func main() {
-	if len(os.Args) != 3 {
-		fmt.Fprintf(os.Stderr, "Usage: %s <config.json> <events.txt>\n",
-			filepath.Base(os.Args[0]))
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "pursuit":
+			runPursuit(os.Args[2:])
+			return
+		case "draw":
+			runDraw(os.Args[2:])
+			return
+		case "serve":
+			runServe(os.Args[2:])
+			return
+		}
+	}
+	runRace()
+}
+
+func runRace() {
+	streamPath := flag.String("stream", "",
+		"файл для объединённого потока входящих и исходящих событий")
+	reportFmt := flag.String("report", "text", "формат отчёта в stdout: text, json, csv, html или xlsx")
+	csvDelim := flag.String("delim", ",", `разделитель колонок CSV (один символ или "tab")`)
+	csvHeader := flag.Bool("header", true, "строка заголовков в CSV")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [-stream out.txt] [-report text|json|csv|html|xlsx] <config.json> <events.txt>\n"+
+			"       %s pursuit [-at HH:MM:SS] <config.json> <sprint_results>\n"+
+			"       %s draw [-seed N] [-groups 1,2;3] [-at HH:MM:SS] [-list file] <config.json> [events.txt]\n"+
+			"       %s serve [-addr :8080] <config.json> [events.txt]\n",
+			filepath.Base(os.Args[0]), filepath.Base(os.Args[0]), filepath.Base(os.Args[0]), filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
 		os.Exit(1)
 	}
-	cfgPath := os.Args[1]
-	evPath := os.Args[2]
+	cfgPath := flag.Arg(0)
+	evPath := flag.Arg(1)
 
 	cfg, err := config.LoadConfig(cfgPath)
 	if err != nil {
 		log.Fatalf("Error loading config: %v", err)
 	}
+	renderer, err := report.RendererFor(*reportFmt)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	if csvR, ok := renderer.(report.CSVRenderer); ok {
+		if csvR.Comma, err = parseDelim(*csvDelim); err != nil {
+			log.Fatalf("Error parsing -delim: %v", err)
+		}
+		csvR.Header = *csvHeader
+		renderer = csvR
+	}
 
-	evs, err := event.LoadEvents(evPath)
+	evF, err := os.Open(evPath)
 	if err != nil {
 		log.Fatalf("Error loading events: %v", err)
 	}
+	defer evF.Close()
 
-	logF, err := os.OpenFile("events.log",
-		os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o666)
+	logger, err := competition.NewFileLogger("events.log")
 	if err != nil {
 		log.Fatalf("Error opening events.log: %v", err)
 	}
-	defer logF.Close()
+	defer logger.Close()
+
+	race := newRace(cfg, logger)
+	if *streamPath != "" {
+		streamF, err := os.Create(*streamPath)
+		if err != nil {
+			log.Fatalf("Error opening %s: %v", *streamPath, err)
+		}
+		defer streamF.Close()
+		race.SetOutput(event.NewWriter(streamF))
+	}
+
+	evR := event.NewReader(evF)
+	evR.File = evPath
+	for ev, err := range evR.All() {
+		if err != nil {
+			log.Fatalf("Error loading events: %v", err)
+		}
+		if err := race.Apply(ev); err != nil && !errors.Is(err, competition.ErrRejected) {
+			log.Fatalf("Error applying events: %v", err)
+		}
+	}
+	race.Finalize()
+	if err := race.Err(); err != nil {
+		log.Fatalf("Error writing %s: %v", *streamPath, err)
+	}
+	res := report.NewResult(cfg, race.Competitors())
+	// resulting_table пишется всегда: по нему строится гонка преследования
+	writeResultingTable(res)
+	if err := renderer.Render(os.Stdout, res); err != nil {
+		log.Fatalf("Error writing report: %v", err)
+	}
+}
 
-	comps := competition.ProcessEvents(cfg, evs)
+// writeResultingTable сохраняет протокол в текстовом формате в resulting_table.
+func writeResultingTable(res *report.Result) {
+	f, err := os.Create("resulting_table")
+	if err != nil {
+		log.Fatalf("Error opening resulting_table: %v", err)
+	}
+	defer f.Close()
+	if err := (report.TextRenderer{}).Render(f, res); err != nil {
+		log.Fatalf("Error writing resulting_table: %v", err)
+	}
+}
+
+// parseDelim разбирает разделитель CSV: один символ или "tab".
+func parseDelim(s string) (rune, error) {
+	if s == "tab" || s == `\t` {
+		return '\t', nil
+	}
+	r := []rune(s)
+	if len(r) != 1 {
+		return 0, fmt.Errorf("ожидается один символ: %q", s)
+	}
+	return r[0], nil
+}
 
-	lines := report.GenerateReport(cfg, comps)
-	for _, l := range lines {
-		fmt.Println(l)
+// newRace создаёт гонку и подключает реестр участников, если он задан.
+func newRace(cfg *config.Config, logger competition.EventLogger) *competition.Race {
+	race := competition.NewRace(cfg, logger)
+	if cfg.Athletes != "" {
+		reg, err := registry.Load(cfg.Athletes)
+		if err != nil {
+			log.Fatalf("Error loading athletes: %v", err)
+		}
+		race.SetRegistry(reg)
 	}
+	return race
 }
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/ironywer/sunny_5_skiers/config"
+	"github.com/ironywer/sunny_5_skiers/event"
+	"github.com/ironywer/sunny_5_skiers/pursuit"
+	"github.com/ironywer/sunny_5_skiers/report"
+)
+
+// runPursuit печатает события 2 стартового протокола гонки преследования,
+// построенного по итоговой таблице спринта.
+func runPursuit(args []string) {
+	fs := flag.NewFlagSet("pursuit", flag.ExitOnError)
+	drawAt := fs.String("at", "",
+		"время событий жеребьёвки (по умолчанию за 30 минут до start)")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s pursuit [-at HH:MM:SS] <config.json> <sprint_results>\n",
+			filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	cfg, err := config.LoadConfig(fs.Arg(0))
+	if err != nil {
+		log.Fatalf("Error loading config: %v", err)
+	}
+
+	at := drawTime(cfg, *drawAt)
+
+	f, err := os.Open(fs.Arg(1))
+	if err != nil {
+		log.Fatalf("Error loading sprint results: %v", err)
+	}
+	defer f.Close()
+	sprint, err := report.ParseResults(f)
+	if err != nil {
+		log.Fatalf("Error loading sprint results: %v", err)
+	}
+
+	entries := pursuit.StartList(cfg.Start, sprint)
+	evs := make([]event.Event, len(entries))
+	for i, e := range entries {
+		evs[i] = e.Event(at)
+	}
+	writeDraws(evs)
+}
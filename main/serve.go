@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/ironywer/sunny_5_skiers/competition"
+	"github.com/ironywer/sunny_5_skiers/config"
+	"github.com/ironywer/sunny_5_skiers/event"
+	"github.com/ironywer/sunny_5_skiers/server"
+)
+
+// runServe запускает HTTP API: события принимаются по мере поступления,
+// положение можно запрашивать в любой момент. Необязательный файл событий
+// применяется до старта сервера.
+func runServe(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", ":8080", "адрес HTTP-сервера")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s serve [-addr :8080] <config.json> [events.txt]\n",
+			filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	cfg, err := config.LoadConfig(fs.Arg(0))
+	if err != nil {
+		log.Fatalf("Error loading config: %v", err)
+	}
+	logger, err := competition.NewFileLogger("events.log")
+	if err != nil {
+		log.Fatalf("Error opening events.log: %v", err)
+	}
+	defer logger.Close()
+
+	srv := server.New(cfg, newRace(cfg, logger))
+
+	if fs.NArg() == 2 {
+		events, err := event.LoadEvents(fs.Arg(1))
+		if err != nil {
+			log.Fatalf("Error loading events: %v", err)
+		}
+		if _, _, err := srv.Apply(events); err != nil {
+			log.Fatalf("Error applying events: %v", err)
+		}
+	}
+
+	log.Printf("Listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, srv.Handler()))
+}
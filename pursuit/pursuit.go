@@ -0,0 +1,56 @@
+package pursuit
+
+import (
+	"sort"
+	"time"
+
+	"github.com/ironywer/sunny_5_skiers/config"
+	"github.com/ironywer/sunny_5_skiers/event"
+	"github.com/ironywer/sunny_5_skiers/report"
+)
+
+// Entry — строка стартового протокола гонки преследования.
+type Entry struct {
+	CompetitorId int
+	StartAt      time.Duration
+	Gap          time.Duration // отставание от лидера спринта в своей категории
+}
+
+// Event возвращает событие 2 строки протокола, помеченное временем жеребьёвки at.
+func (e Entry) Event(at time.Duration) event.Event {
+	return event.Event{Fixtime: at, EventId: 2, CompetitorId: e.CompetitorId, ExtraParams: config.FormatClock(e.StartAt)}
+}
+
+// StartList строит стартовый протокол по результатам спринта: лидер
+// уходит в start, остальные — с отставанием, равным отставанию в спринте.
+// Не финишировавшие в спринте в гонку преследования не допускаются: если
+// в таблице есть места, берутся только строки с местом (строка без места
+// и без статуса — участник, оставшийся на трассе).
+// Если таблица спринта разбита по категориям, отставание считается
+// от лидера своей категории, и лидеры всех категорий уходят в start.
+func StartList(start time.Duration, sprint []report.ParsedRow) []Entry {
+	var placed bool
+	for _, r := range sprint {
+		placed = placed || r.Place > 0
+	}
+	var finished []report.ParsedRow
+	for _, r := range sprint {
+		if r.Status == "" && (r.Place > 0 || !placed) {
+			finished = append(finished, r)
+		}
+	}
+	sort.SliceStable(finished, func(i, j int) bool {
+		return finished[i].Total < finished[j].Total
+	})
+
+	leaders := make(map[string]time.Duration)
+	entries := make([]Entry, len(finished))
+	for i, r := range finished {
+		if _, ok := leaders[r.Category]; !ok {
+			leaders[r.Category] = r.Total
+		}
+		gap := r.Total - leaders[r.Category]
+		entries[i] = Entry{CompetitorId: r.ID, StartAt: start + gap, Gap: gap}
+	}
+	return entries
+}
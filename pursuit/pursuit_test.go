@@ -0,0 +1,95 @@
+package pursuit_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ironywer/sunny_5_skiers/config"
+	"github.com/ironywer/sunny_5_skiers/event"
+	"github.com/ironywer/sunny_5_skiers/pursuit"
+	"github.com/ironywer/sunny_5_skiers/report"
+)
+
+func mustParse(t *testing.T, s string) time.Duration {
+	t.Helper()
+	d, err := config.ParseRowForDuration(s)
+	if err != nil {
+		t.Fatalf("cannot parse duration %q: %v", s, err)
+	}
+	return d
+}
+
+func TestStartListFromSprint(t *testing.T) {
+	t.Parallel()
+
+	table := `1. 00:25:34.773 +00:00:00.000 +00:00:00.000 3 [{00:12:42.386, 4.591}, {00:12:51.500, 4.537}] {,} 10/10
+2. 00:26:58.356 +00:01:23.583 +00:01:23.583 2 [{00:12:38.243, 4.616}, {00:12:38.610, 4.614}] {00:01:40.000, 3.000} 8/10
+00:12:00.000 6 [{00:12:00.000, 4.861}, {,}] {,} 5/5
+[NotFinished] 4 [{00:12:45.669, 4.571}, {,}] {,} 4/5
+[NotStarted] 5 [{,}, {,}] {,} 0/0
+`
+	sprint, err := report.ParseResults(strings.NewReader(table))
+	if err != nil {
+		t.Fatalf("ParseResults returned error: %v", err)
+	}
+	if sprint[1].Place != 2 || sprint[1].Gap != mustParse(t, "00:01:23.583") || sprint[1].ID != 2 {
+		t.Errorf("row = %+v; want place 2, gap 00:01:23.583", sprint[1])
+	}
+
+	entries := pursuit.StartList(mustParse(t, "12:00:00.000"), sprint)
+	want := []pursuit.Entry{
+		{CompetitorId: 3, StartAt: mustParse(t, "12:00:00.000")},
+		{CompetitorId: 2, StartAt: mustParse(t, "12:01:23.583"), Gap: mustParse(t, "00:01:23.583")},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("entries = %+v; want %+v", entries, want)
+	}
+
+	var evs []event.Event
+	var lines []string
+	for _, e := range entries {
+		ev := e.Event(mustParse(t, "11:30:00.000"))
+		evs = append(evs, ev)
+		lines = append(lines, ev.String())
+	}
+	wantLines := []string{
+		"[11:30:00.000] 2 3 12:00:00.000",
+		"[11:30:00.000] 2 2 12:01:23.583",
+	}
+	if !reflect.DeepEqual(lines, wantLines) {
+		t.Errorf("draw lines = %q; want %q", lines, wantLines)
+	}
+	if evs[0].EventId != 2 || evs[0].CompetitorId != 3 || evs[0].Fixtime != mustParse(t, "11:30:00.000") {
+		t.Errorf("unexpected draw event %+v", evs[0])
+	}
+}
+
+func TestStartListByCategory(t *testing.T) {
+	t.Parallel()
+
+	table := `== Men ==
+1. 00:25:00.000 1 #1 A (M) [{00:25:00.000, 4.591}] {,} 5/5
+2. 00:25:30.000 2 #2 B (M) [{00:25:30.000, 4.537}] {,} 5/5
+== Women ==
+1. 00:26:00.000 3 #3 C (W) [{00:26:00.000, 4.616}] {,} 5/5
+`
+	sprint, err := report.ParseResults(strings.NewReader(table))
+	if err != nil {
+		t.Fatalf("ParseResults returned error: %v", err)
+	}
+	if sprint[2].Category != "Women" || sprint[2].Place != 1 {
+		t.Errorf("row = %+v; want Women, place 1", sprint[2])
+	}
+
+	entries := pursuit.StartList(mustParse(t, "12:00:00.000"), sprint)
+	want := []pursuit.Entry{
+		{CompetitorId: 1, StartAt: mustParse(t, "12:00:00.000")},
+		{CompetitorId: 2, StartAt: mustParse(t, "12:00:30.000"), Gap: mustParse(t, "00:00:30.000")},
+		{CompetitorId: 3, StartAt: mustParse(t, "12:00:00.000")},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("entries = %+v; want %+v", entries, want)
+	}
+}
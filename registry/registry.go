@@ -0,0 +1,170 @@
+package registry
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Athlete — карточка участника из реестра.
+type Athlete struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Bib      int    `json:"bib"`
+	Nation   string `json:"nation"`
+	Club     string `json:"club"`
+	Gender   string `json:"gender"`
+	Category string `json:"category"`
+}
+
+// Label — краткая подпись для журнала: "Имя, NAT".
+func (a *Athlete) Label() string {
+	if a.Nation == "" {
+		return a.Name
+	}
+	return a.Name + ", " + a.Nation
+}
+
+// Details — полная подпись для отчёта: "#12 Имя (NAT, клуб, пол, категория)",
+// пустые поля пропускаются.
+func (a *Athlete) Details() string {
+	var s string
+	if a.Bib > 0 {
+		s = fmt.Sprintf("#%d ", a.Bib)
+	}
+	s += a.Name
+	var extra []string
+	for _, v := range []string{a.Nation, a.Club, a.Gender, a.Category} {
+		if v != "" {
+			extra = append(extra, v)
+		}
+	}
+	if len(extra) > 0 {
+		s += " (" + strings.Join(extra, ", ") + ")"
+	}
+	return s
+}
+
+// Registry — реестр участников по ID.
+type Registry struct {
+	athletes map[int]*Athlete
+}
+
+func New(athletes []Athlete) (*Registry, error) {
+	r := &Registry{athletes: make(map[int]*Athlete, len(athletes))}
+	for i := range athletes {
+		a := &athletes[i]
+		if _, ok := r.athletes[a.ID]; ok {
+			return nil, fmt.Errorf("участник %d указан в реестре дважды", a.ID)
+		}
+		r.athletes[a.ID] = a
+	}
+	return r, nil
+}
+
+// Get возвращает участника по ID; nil-реестр пуст.
+func (r *Registry) Get(id int) (*Athlete, bool) {
+	if r == nil {
+		return nil, false
+	}
+	a, ok := r.athletes[id]
+	return a, ok
+}
+
+func (r *Registry) Len() int {
+	if r == nil {
+		return 0
+	}
+	return len(r.athletes)
+}
+
+// IDs возвращает ID всех участников реестра по возрастанию.
+func (r *Registry) IDs() []int {
+	if r == nil {
+		return nil
+	}
+	ids := make([]int, 0, len(r.athletes))
+	for id := range r.athletes {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// Load читает реестр из JSON (массив объектов) или CSV (с заголовком
+// id,name,bib,nation,club,gender,category) в зависимости от расширения.
+func Load(filePath string) (*Registry, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var athletes []Athlete
+	if strings.EqualFold(filepath.Ext(filePath), ".csv") {
+		athletes, err = readCSV(f)
+	} else {
+		err = json.NewDecoder(f).Decode(&athletes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filePath, err)
+	}
+	return New(athletes)
+}
+
+func readCSV(r io.Reader) ([]Athlete, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, err
+	}
+	col := make(map[string]int, len(header))
+	for i, h := range header {
+		col[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	if _, ok := col["id"]; !ok {
+		return nil, fmt.Errorf("в заголовке CSV нет колонки id")
+	}
+	get := func(rec []string, name string) string {
+		if i, ok := col[name]; ok && i < len(rec) {
+			return strings.TrimSpace(rec[i])
+		}
+		return ""
+	}
+
+	var athletes []Athlete
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := cr.FieldPos(0)
+		a := Athlete{
+			Name:     get(rec, "name"),
+			Nation:   get(rec, "nation"),
+			Club:     get(rec, "club"),
+			Gender:   get(rec, "gender"),
+			Category: get(rec, "category"),
+		}
+		if a.ID, err = strconv.Atoi(get(rec, "id")); err != nil {
+			return nil, fmt.Errorf("строка %d: неверный id %q", line, get(rec, "id"))
+		}
+		if bib := get(rec, "bib"); bib != "" {
+			if a.Bib, err = strconv.Atoi(bib); err != nil {
+				return nil, fmt.Errorf("строка %d: неверный bib %q", line, bib)
+			}
+		}
+		athletes = append(athletes, a)
+	}
+	return athletes, nil
+}
@@ -0,0 +1,44 @@
+package registry
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"athletes.json": `[{"id": 1, "name": "Johannes Boe", "bib": 7, "nation": "NOR", "gender": "M"},
+ {"id": 2, "name": "Quentin Fillon Maillet", "nation": "FRA"}]`,
+		"athletes.csv": "id,name,bib,nation,gender\n1,Johannes Boe,7,NOR,M\n2,Quentin Fillon Maillet,,FRA,\n",
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		reg, err := Load(path)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if reg.Len() != 2 || !slices.Equal(reg.IDs(), []int{1, 2}) {
+			t.Errorf("%s: expected 2 athletes, got %d", name, reg.Len())
+		}
+		a, ok := reg.Get(1)
+		if !ok {
+			t.Fatalf("%s: athlete 1 not found", name)
+		}
+		if got := a.Details(); got != "#7 Johannes Boe (NOR, M)" {
+			t.Errorf("%s: Details() = %q", name, got)
+		}
+		if a, _ := reg.Get(2); a.Label() != "Quentin Fillon Maillet, FRA" {
+			t.Errorf("%s: Label() = %q", name, a.Label())
+		}
+	}
+
+	if _, err := New([]Athlete{{ID: 1}, {ID: 1}}); err == nil {
+		t.Error("expected error for duplicate id")
+	}
+}
@@ -0,0 +1,112 @@
+package report
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/ironywer/sunny_5_skiers/config"
+	"github.com/ironywer/sunny_5_skiers/registry"
+)
+
+// CSVRenderer выводит протокол таблицей для электронных таблиц: строка на
+// участника, по две колонки (время и скорость) на каждый круг дистанции.
+// В эстафете строка на этап, впереди — колонки команды.
+type CSVRenderer struct {
+	Comma  rune // разделитель; 0 — запятая
+	Header bool // первой строкой — названия колонок
+}
+
+func (r CSVRenderer) Render(w io.Writer, res *Result) error {
+	cw := csv.NewWriter(w)
+	if r.Comma != 0 {
+		cw.Comma = r.Comma
+	}
+	laps := maxLaps(res.Config)
+	relay := res.Config.Format == config.FormatRelay
+
+	if r.Header {
+		var head []string
+		if relay {
+			head = append(head, "team_place", "team_id", "team", "team_status", "team_total", "leg")
+		}
+		head = append(head, "place", "id", "bib", "name", "nation", "club", "gender", "category",
+			"status", "total", "gap", "behind")
+		for i := 1; i <= laps; i++ {
+			head = append(head, fmt.Sprintf("lap%d_time", i), fmt.Sprintf("lap%d_speed", i))
+		}
+		head = append(head, "penalty_time", "penalty_speed", "penalty_loops", "hits", "shots", "spares", "miss_penalty")
+		if err := cw.Write(head); err != nil {
+			return err
+		}
+	}
+
+	if relay {
+		for _, t := range res.Teams {
+			total, _, _ := RowTimes(t.Place, t.Status, t.Total, 0, 0)
+			team := []string{csvInt(t.Place), strconv.Itoa(t.ID), t.Name, string(t.Status), total}
+			for _, leg := range t.Legs {
+				rec := append(append([]string{}, team...), strconv.Itoa(leg.Leg))
+				if err := cw.Write(append(rec, csvRow(leg, laps)...)); err != nil {
+					return err
+				}
+			}
+		}
+	} else {
+		for _, row := range res.Rows {
+			if err := cw.Write(csvRow(row, laps)); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func csvRow(r ResultRow, laps int) []string {
+	var a registry.Athlete
+	if r.Athlete != nil {
+		a = *r.Athlete
+	}
+	total, gap, behind := RowTimes(r.Place, r.Status, r.Total, r.Gap, r.Behind)
+	rec := []string{csvInt(r.Place), strconv.Itoa(r.ID), csvInt(a.Bib), a.Name, a.Nation, a.Club, a.Gender,
+		r.Category, string(r.Status), total, gap, behind}
+	for i := 0; i < laps; i++ {
+		var l *Lap
+		if i < len(r.Laps) {
+			l = r.Laps[i]
+		}
+		rec = append(rec, csvLap(l)...)
+	}
+	rec = append(rec, csvLap(r.Penalty)...)
+	var missPenalty string
+	if r.MissPenalty > 0 {
+		missPenalty = format(r.MissPenalty)
+	}
+	return append(rec, strconv.Itoa(r.PenaltyLoops), strconv.Itoa(r.Hits), strconv.Itoa(r.Shots),
+		strconv.Itoa(r.Spares), missPenalty)
+}
+
+func csvLap(l *Lap) []string {
+	if l == nil {
+		return []string{"", ""}
+	}
+	return []string{format(l.Time), fmt.Sprintf("%.3f", l.Speed)}
+}
+
+func csvInt(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+// maxLaps — число колонок кругов: наибольшая дистанция среди категорий.
+func maxLaps(cfg *config.Config) int {
+	n := cfg.Laps
+	for _, cat := range cfg.Categories {
+		n = max(n, cat.Laps)
+	}
+	return n
+}
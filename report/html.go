@@ -0,0 +1,265 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"time"
+
+	"github.com/ironywer/sunny_5_skiers/config"
+)
+
+// HTMLRenderer выводит протокол одной HTML-страницей без внешних файлов:
+// стили и скрипт сортировки встроены. В шапке — параметры гонки, таблица
+// делится по категориям (в эстафете — по командам), столбцы сортируются
+// щелчком по заголовку.
+type HTMLRenderer struct {
+	Title string // заголовок страницы; по умолчанию "Results"
+}
+
+func (r HTMLRenderer) Render(w io.Writer, res *Result) error {
+	title := r.Title
+	if title == "" {
+		title = "Results"
+	}
+	page := htmlPage{Title: title, Race: newJSONRace(res.Config), Laps: make([]int, maxLaps(res.Config))}
+	for i := range page.Laps {
+		page.Laps[i] = i + 1
+	}
+
+	switch {
+	case res.Config.Format == config.FormatRelay:
+		for _, t := range res.Teams {
+			total, gap, _ := RowTimes(t.Place, t.Status, t.Total, t.Gap, t.Behind)
+			sec := htmlSection{Title: fmt.Sprintf("%d %s", t.ID, t.Name), Status: t.Status, Total: total}
+			if t.Place > 0 {
+				sec.Title = fmt.Sprintf("%d. %s", t.Place, sec.Title)
+				sec.Gap = "+" + gap
+			}
+			for _, leg := range t.Legs {
+				sec.Rows = append(sec.Rows, newHTMLRow(res.Config, leg, len(page.Laps)))
+			}
+			page.Sections = append(page.Sections, sec)
+		}
+	default:
+		for i, row := range res.Rows {
+			if i == 0 || res.ByCategory && res.Rows[i-1].Category != row.Category {
+				var sec htmlSection
+				if res.ByCategory {
+					sec.Title = row.Category
+					if sec.Title == "" {
+						sec.Title = Uncategorized
+					}
+				}
+				page.Sections = append(page.Sections, sec)
+			}
+			sec := &page.Sections[len(page.Sections)-1]
+			sec.Rows = append(sec.Rows, newHTMLRow(res.Config, row, len(page.Laps)))
+		}
+	}
+	return htmlTemplate.Execute(w, page)
+}
+
+type htmlPage struct {
+	Title    string
+	Race     jsonRace
+	Laps     []int
+	Sections []htmlSection
+}
+
+// htmlSection — таблица категории или команды эстафеты.
+type htmlSection struct {
+	Title  string
+	Status Status
+	Total  string
+	Gap    string
+	Rows   []htmlRow
+}
+
+// htmlCell — текст ячейки и ключ сортировки (миллисекунды, число или строка).
+type htmlCell struct {
+	Text  string
+	Sub   string // вторая строка мелким шрифтом (скорость)
+	Order string
+}
+
+type htmlRow struct {
+	Place    htmlCell
+	ID       int
+	Leg      int
+	Bib      htmlCell
+	Name     string
+	Nation   string
+	Club     string
+	Gender   string
+	Status   Status
+	Total    htmlCell
+	Gap      htmlCell
+	Behind   htmlCell
+	Laps     []htmlCell
+	Penalty  htmlCell
+	Shooting htmlCell
+}
+
+// sortLast — ключ сортировки для пустых ячеек: они уходят в конец.
+const sortLast = "999999999999"
+
+func newHTMLRow(cfg *config.Config, r ResultRow, laps int) htmlRow {
+	row := htmlRow{ID: r.ID, Leg: r.Leg, Status: r.Status}
+	row.Bib = htmlCell{Order: sortLast}
+	if a := r.Athlete; a != nil {
+		row.Name, row.Nation, row.Club, row.Gender = a.Name, a.Nation, a.Club, a.Gender
+		if a.Bib > 0 {
+			row.Bib = htmlCell{Text: fmt.Sprint(a.Bib), Order: fmt.Sprint(a.Bib)}
+		}
+	}
+	row.Place = htmlCell{Order: sortLast}
+	if r.Place > 0 {
+		row.Place = htmlCell{Text: fmt.Sprint(r.Place), Order: fmt.Sprint(r.Place)}
+	}
+
+	total, gap, behind := RowTimes(r.Place, r.Status, r.Total, r.Gap, r.Behind)
+	row.Total = timeCell(total, r.Total)
+	row.Gap = timeCell(gap, r.Gap)
+	row.Behind = timeCell(behind, r.Behind)
+	if gap != "" {
+		row.Gap.Text = "+" + gap
+		row.Behind.Text = "+" + behind
+	}
+
+	row.Laps = make([]htmlCell, laps)
+	for i := range row.Laps {
+		row.Laps[i] = lapCell(nil)
+		if i < len(r.Laps) {
+			row.Laps[i] = lapCell(r.Laps[i])
+		}
+	}
+	row.Penalty = lapCell(r.Penalty)
+	if r.Penalty != nil {
+		row.Penalty.Text = fmt.Sprintf("%s (%d)", row.Penalty.Text, r.PenaltyLoops)
+	}
+
+	row.Shooting = htmlCell{Text: fmt.Sprintf("%d/%d", r.Hits, r.Shots), Order: fmt.Sprint(r.Shots - r.Hits)}
+	if cfg.SparesPerBout() > 0 {
+		row.Shooting.Text += fmt.Sprintf("+%d", r.Spares)
+	}
+	if r.MissPenalty > 0 {
+		row.Shooting.Sub = "+" + format(r.MissPenalty)
+	}
+	return row
+}
+
+func timeCell(text string, d time.Duration) htmlCell {
+	if text == "" {
+		return htmlCell{Order: sortLast}
+	}
+	return htmlCell{Text: text, Order: fmt.Sprint(d.Milliseconds())}
+}
+
+func lapCell(l *Lap) htmlCell {
+	if l == nil {
+		return htmlCell{Order: sortLast}
+	}
+	return htmlCell{
+		Text:  format(l.Time),
+		Sub:   fmt.Sprintf("%.3f m/s", l.Speed),
+		Order: fmt.Sprint(l.Time.Milliseconds()),
+	}
+}
+
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 1.5rem; color: #1d2733; background: #f5f7fa; }
+h1 { margin: 0 0 .5rem; }
+h2 { margin: 1.5rem 0 .5rem; font-size: 1.2rem; }
+.race { display: flex; flex-wrap: wrap; gap: .5rem 1.5rem; margin: 0 0 1rem; padding: 0; list-style: none; color: #4a5a6b; }
+.race b { color: #1d2733; }
+table { border-collapse: collapse; width: 100%; background: #fff; box-shadow: 0 1px 3px rgba(0,0,0,.1); }
+th, td { padding: .35rem .6rem; text-align: left; border-bottom: 1px solid #e3e8ee; white-space: nowrap; }
+th { background: #24415f; color: #fff; cursor: pointer; user-select: none; position: sticky; top: 0; }
+th.asc::after { content: " \25B2"; }
+th.desc::after { content: " \25BC"; }
+tbody tr:nth-child(even) { background: #f9fbfd; }
+td.num { text-align: right; font-variant-numeric: tabular-nums; }
+small { display: block; color: #6b7b8c; }
+.badge { display: inline-block; padding: .1rem .45rem; border-radius: .6rem; font-size: .8rem; font-weight: 600; }
+.Finished { background: #d7f2dd; color: #1e6b33; }
+.Racing { background: #dde9fa; color: #24518f; }
+.NotFinished { background: #fde7c8; color: #8a5200; }
+.NotStarted { background: #f8d4d4; color: #962020; }
+.NotReached { background: #e6e9ed; color: #4a5a6b; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<ul class="race">
+<li>Format: <b>{{.Race.Format}}</b></li>
+<li>Laps: <b>{{.Race.Laps}}</b></li>
+<li>Lap length: <b>{{.Race.LapLen}} m</b></li>
+<li>Penalty lap: <b>{{.Race.PenaltyLen}} m</b></li>
+<li>Firing lines: <b>{{.Race.FiringLines}}</b></li>
+<li>Start: <b>{{.Race.Start}}</b></li>
+<li>Start interval: <b>{{.Race.StartDelta}}</b></li>
+<li>Scoring: <b>{{.Race.Scoring}}</b></li>
+</ul>
+{{range .Sections}}
+{{if .Title}}<h2>{{.Title}}{{if .Total}} — {{.Total}}{{end}}{{if .Gap}} ({{.Gap}}){{end}}{{if .Status}} <span class="badge {{.Status}}">{{.Status}}</span>{{end}}</h2>{{end}}
+<table class="sortable">
+<thead><tr>
+<th>Place</th>{{if and .Rows (index .Rows 0).Leg}}<th>Leg</th>{{end}}<th>ID</th><th>Bib</th><th>Name</th><th>Nation</th><th>Club</th><th>Gender</th><th>Status</th>
+<th>Total</th><th>Gap</th><th>Behind</th>{{range $.Laps}}<th>Lap {{.}}</th>{{end}}<th>Penalty</th><th>Shooting</th>
+</tr></thead>
+<tbody>
+{{- range .Rows}}
+<tr>
+<td class="num" data-order="{{.Place.Order}}">{{.Place.Text}}</td>
+{{- if .Leg}}<td class="num" data-order="{{.Leg}}">{{.Leg}}</td>{{end}}
+<td class="num" data-order="{{.ID}}">{{.ID}}</td>
+<td class="num" data-order="{{.Bib.Order}}">{{.Bib.Text}}</td>
+<td>{{.Name}}</td>
+<td>{{.Nation}}</td>
+<td>{{.Club}}</td>
+<td>{{.Gender}}</td>
+<td data-order="{{.Status}}"><span class="badge {{.Status}}">{{.Status}}</span></td>
+<td class="num" data-order="{{.Total.Order}}">{{.Total.Text}}</td>
+<td class="num" data-order="{{.Gap.Order}}">{{.Gap.Text}}</td>
+<td class="num" data-order="{{.Behind.Order}}">{{.Behind.Text}}</td>
+{{- range .Laps}}
+<td class="num" data-order="{{.Order}}">{{.Text}}{{if .Sub}}<small>{{.Sub}}</small>{{end}}</td>
+{{- end}}
+<td class="num" data-order="{{.Penalty.Order}}">{{.Penalty.Text}}{{if .Penalty.Sub}}<small>{{.Penalty.Sub}}</small>{{end}}</td>
+<td class="num" data-order="{{.Shooting.Order}}">{{.Shooting.Text}}{{if .Shooting.Sub}}<small>{{.Shooting.Sub}}</small>{{end}}</td>
+</tr>
+{{- end}}
+</tbody>
+</table>
+{{end}}
+<script>
+document.querySelectorAll("table.sortable").forEach(function (table) {
+  table.querySelectorAll("th").forEach(function (th, col) {
+    th.addEventListener("click", function () {
+      var asc = !th.classList.contains("asc");
+      table.querySelectorAll("th").forEach(function (h) { h.classList.remove("asc", "desc"); });
+      th.classList.add(asc ? "asc" : "desc");
+      var body = table.tBodies[0];
+      var rows = Array.prototype.slice.call(body.rows);
+      rows.sort(function (a, b) {
+        var x = a.cells[col].dataset.order || a.cells[col].textContent;
+        var y = b.cells[col].dataset.order || b.cells[col].textContent;
+        var nx = Number(x), ny = Number(y);
+        var cmp = !isNaN(nx) && !isNaN(ny) ? nx - ny : x.localeCompare(y);
+        return asc ? cmp : -cmp;
+      });
+      rows.forEach(function (r) { body.appendChild(r); });
+    });
+  });
+});
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,252 @@
+package report
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"time"
+
+	"github.com/ironywer/sunny_5_skiers/competition"
+	"github.com/ironywer/sunny_5_skiers/config"
+	"github.com/ironywer/sunny_5_skiers/registry"
+)
+
+// JSONSchemaVersion — версия схемы JSONRenderer. Увеличивается при
+// несовместимых изменениях; новые необязательные поля версию не меняют.
+const JSONSchemaVersion = 1
+
+// JSONRenderer выводит протокол одним JSON-документом:
+//
+//	{
+//	  "schemaVersion": 1,
+//	  "race": {"format", "laps", "lapLen", "penaltyLen", "firingLines",
+//	           "start", "startDelta", "scoring"},
+//	  "byCategory": false,
+//	  "results": [строка участника],   // кроме эстафеты
+//	  "teams": [{"place", "id", "name", "status", "total", "gap", "behind",
+//	             "legs": [строка участника]}]   // только эстафета
+//	}
+//
+// Строка участника:
+//
+//	{"place", "id", "athlete", "category", "leg", "status", "total", "gap", "behind",
+//	 "laps": [{"time", "speed"} | null],
+//	 "penalty": {"time", "speed", "loops"} | null,
+//	 "shooting": {"hits", "shots", "spares", "missPenalty",
+//	              "bouts": [{"range", "lap", "enteredAt", "leftAt", "hits", "shots", "spares"}]},
+//	 "events": [{"time", "eventId"}]}
+//
+// Время — строка "HH:MM:SS.sss", скорость — м/с с точностью до 0.001.
+// status — Finished, Racing, NotFinished, NotStarted или NotReached (этап
+// эстафеты, до которого не дошла передача). place, gap и behind
+// есть только у участников с местом, total — только у Finished и Racing.
+type JSONRenderer struct {
+	Indent bool // выводить с отступами
+}
+
+func (r JSONRenderer) Render(w io.Writer, res *Result) error {
+	doc := jsonDoc{
+		SchemaVersion: JSONSchemaVersion,
+		Race:          newJSONRace(res.Config),
+		ByCategory:    res.ByCategory,
+	}
+	for _, row := range res.Rows {
+		doc.Results = append(doc.Results, newJSONRow(row))
+	}
+	for _, t := range res.Teams {
+		jt := jsonTeam{
+			Place:  t.Place,
+			ID:     t.ID,
+			Name:   t.Name,
+			Status: t.Status,
+			Legs:   make([]jsonRow, len(t.Legs)),
+		}
+		jt.Total, jt.Gap, jt.Behind = jsonTimes(t.Place, t.Status, t.Total, t.Gap, t.Behind)
+		for i, leg := range t.Legs {
+			jt.Legs[i] = newJSONRow(leg)
+		}
+		doc.Teams = append(doc.Teams, jt)
+	}
+
+	enc := json.NewEncoder(w)
+	if r.Indent {
+		enc.SetIndent("", "  ")
+	}
+	return enc.Encode(doc)
+}
+
+type jsonDoc struct {
+	SchemaVersion int        `json:"schemaVersion"`
+	Race          jsonRace   `json:"race"`
+	ByCategory    bool       `json:"byCategory"`
+	Results       []jsonRow  `json:"results,omitempty"`
+	Teams         []jsonTeam `json:"teams,omitempty"`
+}
+
+type jsonRace struct {
+	Format      string `json:"format"`
+	Laps        int    `json:"laps"`
+	LapLen      int    `json:"lapLen"`
+	PenaltyLen  int    `json:"penaltyLen"`
+	FiringLines int    `json:"firingLines"`
+	Start       string `json:"start"`
+	StartDelta  string `json:"startDelta"`
+	Scoring     string `json:"scoring"`
+}
+
+func newJSONRace(cfg *config.Config) jsonRace {
+	r := jsonRace{
+		Format:      cfg.Format,
+		Laps:        cfg.Laps,
+		LapLen:      cfg.LapLen,
+		PenaltyLen:  cfg.PenaltyLen,
+		FiringLines: cfg.FiringLines,
+		Start:       format(cfg.Start),
+		StartDelta:  format(cfg.StartDelta),
+		Scoring:     cfg.Scoring,
+	}
+	if r.Format == "" {
+		r.Format = config.FormatInterval
+	}
+	if r.Scoring == "" {
+		r.Scoring = config.ScoringLoop
+	}
+	return r
+}
+
+type jsonTeam struct {
+	Place  int       `json:"place,omitempty"`
+	ID     int       `json:"id"`
+	Name   string    `json:"name"`
+	Status Status    `json:"status"`
+	Total  *string   `json:"total,omitempty"`
+	Gap    *string   `json:"gap,omitempty"`
+	Behind *string   `json:"behind,omitempty"`
+	Legs   []jsonRow `json:"legs"`
+}
+
+type jsonRow struct {
+	Place    int               `json:"place,omitempty"`
+	ID       int               `json:"id"`
+	Athlete  *registry.Athlete `json:"athlete,omitempty"`
+	Category string            `json:"category,omitempty"`
+	Leg      int               `json:"leg,omitempty"`
+	Status   Status            `json:"status"`
+	Total    *string           `json:"total,omitempty"`
+	Gap      *string           `json:"gap,omitempty"`
+	Behind   *string           `json:"behind,omitempty"`
+	Laps     []*jsonLap        `json:"laps"`
+	Penalty  *jsonPenalty      `json:"penalty"`
+	Shooting jsonShooting      `json:"shooting"`
+	Events   []jsonEvent       `json:"events"`
+}
+
+type jsonLap struct {
+	Time  string   `json:"time"`
+	Speed *float64 `json:"speed"`
+}
+
+type jsonPenalty struct {
+	jsonLap
+	Loops int `json:"loops"`
+}
+
+type jsonShooting struct {
+	Hits        int        `json:"hits"`
+	Shots       int        `json:"shots"`
+	Spares      int        `json:"spares"`
+	MissPenalty string     `json:"missPenalty,omitempty"`
+	Bouts       []jsonBout `json:"bouts"`
+}
+
+type jsonBout struct {
+	Range     int    `json:"range"`
+	Lap       int    `json:"lap"`
+	EnteredAt string `json:"enteredAt"`
+	LeftAt    string `json:"leftAt,omitempty"`
+	Hits      int    `json:"hits"`
+	Shots     int    `json:"shots"`
+	Spares    int    `json:"spares"`
+}
+
+type jsonEvent struct {
+	Time    string `json:"time"`
+	EventId int    `json:"eventId"`
+}
+
+func newJSONRow(r ResultRow) jsonRow {
+	j := jsonRow{
+		Place:    r.Place,
+		ID:       r.ID,
+		Athlete:  r.Athlete,
+		Category: r.Category,
+		Leg:      r.Leg,
+		Status:   r.Status,
+		Laps:     make([]*jsonLap, len(r.Laps)),
+		Shooting: jsonShooting{
+			Hits:   r.Hits,
+			Shots:  r.Shots,
+			Spares: r.Spares,
+			Bouts:  make([]jsonBout, len(r.Bouts)),
+		},
+		Events: make([]jsonEvent, len(r.Outgoing)),
+	}
+	j.Total, j.Gap, j.Behind = jsonTimes(r.Place, r.Status, r.Total, r.Gap, r.Behind)
+	for i, l := range r.Laps {
+		if l != nil {
+			j.Laps[i] = newJSONLap(*l)
+		}
+	}
+	if r.Penalty != nil {
+		j.Penalty = &jsonPenalty{jsonLap: *newJSONLap(*r.Penalty), Loops: r.PenaltyLoops}
+	}
+	if r.MissPenalty > 0 {
+		j.Shooting.MissPenalty = format(r.MissPenalty)
+	}
+	for i, b := range r.Bouts {
+		j.Shooting.Bouts[i] = newJSONBout(b)
+	}
+	for i, ev := range r.Outgoing {
+		j.Events[i] = jsonEvent{Time: format(ev.Time), EventId: ev.EventId}
+	}
+	return j
+}
+
+func newJSONLap(l Lap) *jsonLap {
+	j := &jsonLap{Time: format(l.Time)}
+	// у старых протоколов нулевое время круга даёт бесконечную скорость
+	if !math.IsInf(l.Speed, 0) && !math.IsNaN(l.Speed) {
+		s := math.Round(l.Speed*1000) / 1000
+		j.Speed = &s
+	}
+	return j
+}
+
+func newJSONBout(b competition.Bout) jsonBout {
+	j := jsonBout{
+		Range:     b.Range,
+		Lap:       b.Lap,
+		EnteredAt: format(b.EnteredAt),
+		Hits:      b.Hits(),
+		Shots:     b.Shots,
+		Spares:    b.Spares,
+	}
+	if b.LeftAt > 0 {
+		j.LeftAt = format(b.LeftAt)
+	}
+	return j
+}
+
+// jsonTimes возвращает total, gap и behind; nil — поля нет в документе.
+func jsonTimes(place int, status Status, total, gap, behind time.Duration) (t, g, b *string) {
+	ts, gs, bs := RowTimes(place, status, total, gap, behind)
+	return ptr(ts), ptr(gs), ptr(bs)
+}
+
+// ptr возвращает nil для пустой строки.
+func ptr(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,91 @@
+package report
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ironywer/sunny_5_skiers/config"
+)
+
+// ParsedRow — строка итоговой таблицы, прочитанная обратно из текста.
+type ParsedRow struct {
+	ID       int
+	Status   string // NotStarted, NotFinished, NotReached или пусто для финишировавших
+	Total    time.Duration
+	Category string        // категория из заголовка протокола, если таблица разбита по категориям
+	Place    int           // место, 0 — без места
+	Gap      time.Duration // отставание от лидера
+	Behind   time.Duration // отставание от предыдущего
+}
+
+// ParseResults читает таблицу в формате GenerateReport (например, resulting_table).
+// Строки участников без категории получают пустую Category.
+func ParseResults(r io.Reader) ([]ParsedRow, error) {
+	var rows []ParsedRow
+	scanner := bufio.NewScanner(r)
+	line := 0
+	category := ""
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(text, "==") {
+			category = strings.TrimSpace(strings.Trim(text, "="))
+			if category == Uncategorized {
+				category = ""
+			}
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		row := ParsedRow{Category: category}
+		if p, ok := strings.CutSuffix(fields[0], "."); ok {
+			place, err := strconv.Atoi(p)
+			if err != nil {
+				return nil, fmt.Errorf("строка %d: неверное место %q", line, fields[0])
+			}
+			row.Place = place
+			fields = fields[1:]
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("строка %d: неполная строка отчёта", line)
+		}
+		if strings.HasPrefix(fields[0], "[") {
+			row.Status = strings.Trim(fields[0], "[]")
+		} else {
+			total, err := config.ParseRowForDuration(fields[0])
+			if err != nil {
+				return nil, fmt.Errorf("строка %d: %w", line, err)
+			}
+			row.Total = total
+			if row.Place > 0 && len(fields) > 3 && strings.HasPrefix(fields[1], "+") {
+				if row.Gap, err = parseGap(fields[1]); err != nil {
+					return nil, fmt.Errorf("строка %d: %w", line, err)
+				}
+				if row.Behind, err = parseGap(fields[2]); err != nil {
+					return nil, fmt.Errorf("строка %d: %w", line, err)
+				}
+				fields = fields[2:]
+			}
+		}
+		id, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("строка %d: неверный ID участника %q", line, fields[1])
+		}
+		row.ID = id
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+func parseGap(s string) (time.Duration, error) {
+	return config.ParseRowForDuration(strings.TrimPrefix(s, "+"))
+}
@@ -0,0 +1,13 @@
+package report
+
+import (
+	"github.com/ironywer/sunny_5_skiers/competition"
+	"github.com/ironywer/sunny_5_skiers/config"
+)
+
+// GenerateRelayReport — отчёт эстафеты: строка команды с местом, итоговым
+// временем и отставаниями (или статусом), под ней строки этапов в формате
+// GenerateReport.
+func GenerateRelayReport(cfg *config.Config, comps map[int]*competition.Competitor) []string {
+	return TextRenderer{}.Lines(relayResult(cfg, comps))
+}
@@ -2,116 +2,54 @@ package report
 
 import (
 	"fmt"
-	"os"
-	"sort"
-	"strings"
+	"io"
 	"time"
 
 	"github.com/ironywer/sunny_5_skiers/competition"
 	"github.com/ironywer/sunny_5_skiers/config"
 )
 
-type reportRow struct {
-	status     string
-	id         int
-	deltaStart time.Duration
-	total      time.Duration
-	totalStr   string
-	lapStr     string
-	penStr     string
-	hitsShot   string
+// Renderer выводит протокол в одном из форматов.
+type Renderer interface {
+	Render(w io.Writer, res *Result) error
 }
 
-func GenerateReport(cfg *config.Config, comps map[int]*competition.Competitor) []string {
-
-	format := func(d time.Duration) string {
-		return config.FormatClock(d)
-	}
-
-	var rows []reportRow
-	for _, c := range comps {
-		var r reportRow
-		r.id = c.ID
-		r.deltaStart = c.ActualStart - c.ScheduledAt
-
-		switch {
-		case c.NotStarted:
-			r.status = "NotStarted"
-		case c.NotFinished:
-			r.status = "NotFinished"
-		default:
-
-			offset := c.ActualStart - c.ScheduledAt
-			var lapsSum time.Duration
-			for _, lap := range c.LapTimes {
-				lapsSum += lap
-			}
-			tot := offset + lapsSum + c.PenaltyTime
-			r.total = tot
-			r.totalStr = format(tot)
-		}
-
-		lapEntries := make([]string, cfg.Laps)
-		for i := 0; i < cfg.Laps; i++ {
-			if i < len(c.LapTimes) {
-				lt := c.LapTimes[i]
-				if r.status == "NotFinished" && i == len(c.LapTimes)-1 || r.status == "NotStarted" {
-					lt = r.deltaStart
-				}
-				speed := float64(cfg.LapLen) / lt.Seconds()
-				lapEntries[i] = fmt.Sprintf("{%s, %.3f}", format(lt), speed)
-			} else {
-				lapEntries[i] = "{,}"
-			}
-		}
-		r.lapStr = fmt.Sprintf("[%s]", strings.Join(lapEntries, ", "))
-
-		if c.PenaltyCount > 0 && c.PenaltyTime > 0 {
-			penSpeed := float64(cfg.PenaltyLen*c.PenaltyCount) / c.PenaltyTime.Seconds()
-			r.penStr = fmt.Sprintf("{%s, %.3f}", format(c.PenaltyTime), penSpeed)
-		} else {
-			r.penStr = "{,}"
-		}
-
-		shots := c.Hits + c.PenaltyCount
-		r.hitsShot = fmt.Sprintf("%d/%d", c.Hits, shots)
-
-		rows = append(rows, r)
+// RendererFor возвращает рендерер по имени формата: text, json, csv, html или xlsx.
+func RendererFor(name string) (Renderer, error) {
+	switch name {
+	case "", "text":
+		return TextRenderer{}, nil
+	case "json":
+		return JSONRenderer{Indent: true}, nil
+	case "csv":
+		return CSVRenderer{Comma: ',', Header: true}, nil
+	case "html":
+		return HTMLRenderer{}, nil
+	case "xlsx":
+		return XLSXRenderer{}, nil
 	}
+	return nil, fmt.Errorf("неизвестный формат отчёта: %s", name)
+}
 
-	sort.Slice(rows, func(i, j int) bool {
-		a, b := rows[i], rows[j]
-
-		if a.status == "" && b.status != "" {
-			return true
-		}
-		if a.status != "" && b.status == "" {
-			return false
-		}
+func format(d time.Duration) string {
+	return config.FormatClock(d)
+}
 
-		if a.status == "" && b.status == "" {
-			return a.total < b.total
-		}
+// GenerateReport строит итоговую таблицу. Финишировавшие получают место
+// (равный результат делит место), отставание от лидера и от предыдущего.
+// Если у участников есть категории, таблица делится на протоколы категорий
+// с заголовком "== Категория ==", и места считаются внутри категории.
+// Оставлена для совместимости: то же, что TextRenderer.Lines над NewResult.
+func GenerateReport(cfg *config.Config, comps map[int]*competition.Competitor) []string {
+	return TextRenderer{}.Lines(individualResult(cfg, comps))
+}
 
-		order := map[string]int{"NotFinished": 0, "NotStarted": 1}
-		return order[a.status] < order[b.status]
-	})
+// Uncategorized — заголовок протокола участников без категории.
+const Uncategorized = "Uncategorized"
 
-	var out []string
-	f, err := os.OpenFile("resulting_table", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o666)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error opening resulting_table: %v\n", err)
-		return nil
-	}
-	defer f.Close()
-	for _, r := range rows {
-		first := r.totalStr
-		if r.status != "" {
-			first = fmt.Sprintf("[%s]", r.status)
-		}
-		line := fmt.Sprintf("%s %d %s %s %s", first, r.id, r.lapStr, r.penStr, r.hitsShot)
-		out = append(out, line)
-		f.Write([]byte(line + "\n"))
+func categoryHeader(cat string) string {
+	if cat == "" {
+		cat = Uncategorized
 	}
-	return out
+	return "== " + cat + " =="
 }
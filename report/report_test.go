@@ -1,14 +1,20 @@
 package report_test
 
 import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
 	"os"
-	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/ironywer/sunny_5_skiers/competition"
 	"github.com/ironywer/sunny_5_skiers/config"
+	"github.com/ironywer/sunny_5_skiers/registry"
 	"github.com/ironywer/sunny_5_skiers/report"
 )
 
@@ -24,16 +30,6 @@ func mustParse(t *testing.T, s string) time.Duration {
 func TestGenerateReport(t *testing.T) {
 	t.Parallel()
 
-	dir := t.TempDir()
-	origWd, err := os.Getwd()
-	if err != nil {
-		t.Fatalf("getwd: %v", err)
-	}
-	defer os.Chdir(origWd)
-	if err := os.Chdir(dir); err != nil {
-		t.Fatalf("chdir: %v", err)
-	}
-
 	cfg := &config.Config{
 		Laps:        2,
 		LapLen:      1000,
@@ -48,10 +44,16 @@ func TestGenerateReport(t *testing.T) {
 			ID:           1,
 			ScheduledAt:  mustParse(t, "00:00:00.000"),
 			ActualStart:  mustParse(t, "00:00:10.000"),
+			Started:      true,
+			Finished:     true,
 			LapTimes:     []time.Duration{mustParse(t, "00:00:30.000"), mustParse(t, "00:00:40.000")},
 			PenaltyCount: 1,
 			PenaltyTime:  mustParse(t, "00:00:20.000"),
-			Hits:         4,
+			PenaltyVisits: []competition.PenaltyVisit{
+				{EnteredAt: mustParse(t, "00:00:20.000"), LeftAt: mustParse(t, "00:00:40.000"), Loops: 1},
+			},
+			Hits:  4,
+			Shots: 5,
 		},
 		2: {
 			ID:          2,
@@ -66,14 +68,15 @@ func TestGenerateReport(t *testing.T) {
 			LapTimes:    []time.Duration{mustParse(t, "00:00:25.000")},
 			NotFinished: true,
 			Hits:        2,
+			Shots:       5,
 		},
 	}
 
 	lines := report.GenerateReport(cfg, comps)
 
 	want := []string{
-		"00:01:40.000 1 [{00:00:30.000, 33.333}, {00:00:40.000, 25.000}] {00:00:20.000, 5.000} 4/5",
-		"[NotFinished] 3 [{00:00:10.000, 100.000}, {,}] {,} 2/2",
+		"1. 00:01:40.000 +00:00:00.000 +00:00:00.000 1 [{00:00:30.000, 33.333}, {00:00:40.000, 25.000}] {00:00:20.000, 5.000} 4/5",
+		"[NotFinished] 3 [{00:00:10.000, 100.000}, {,}] {,} 2/5",
 		"[NotStarted] 2 [{,}, {,}] {,} 0/0",
 	}
 
@@ -86,17 +89,435 @@ func TestGenerateReport(t *testing.T) {
 		}
 	}
 
-	data, err := os.ReadFile(filepath.Join(dir, "resulting_table"))
+	// GenerateReport — обёртка над TextRenderer и файлов не пишет
+	var buf bytes.Buffer
+	if err := (report.TextRenderer{}).Render(&buf, report.NewResult(cfg, comps)); err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if got := strings.Join(want, "\n") + "\n"; buf.String() != got {
+		t.Errorf("rendered %q; want %q", buf.String(), got)
+	}
+}
+
+func TestGenerateReport_TimeScoring(t *testing.T) {
+	dir := t.TempDir()
+	origWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd: %v", err)
+	}
+	defer os.Chdir(origWd)
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+
+	cfg := &config.Config{
+		Laps:        1,
+		LapLen:      1000,
+		PenaltyLen:  100,
+		FiringLines: 1,
+		StartDelta:  mustParse(t, "00:00:30.000"),
+		Scoring:     config.ScoringTime,
+		MissPenalty: mustParse(t, "00:00:45.000"),
+	}
+
+	comps := map[int]*competition.Competitor{
+		1: {
+			ID:          1,
+			Started:     true,
+			Finished:    true,
+			LapTimes:    []time.Duration{mustParse(t, "00:01:00.000")},
+			Hits:        3,
+			Shots:       5,
+			MissPenalty: mustParse(t, "00:01:30.000"),
+		},
+	}
+
+	lines := report.GenerateReport(cfg, comps)
+
+	want := "1. 00:02:30.000 +00:00:00.000 +00:00:00.000 1 [{00:01:00.000, 16.667}] {,} 3/5 +00:01:30.000"
+	if len(lines) != 1 || lines[0] != want {
+		t.Errorf("lines = %q; want %q", lines, want)
+	}
+}
+
+func TestGenerateRelayReport(t *testing.T) {
+	dir := t.TempDir()
+	origWd, err := os.Getwd()
 	if err != nil {
-		t.Fatalf("reading resulting_table: %v", err)
+		t.Fatalf("getwd: %v", err)
+	}
+	defer os.Chdir(origWd)
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+
+	cfg := &config.Config{
+		Laps:   1,
+		LapLen: 1000,
+		Format: config.FormatRelay,
+		Teams: []config.Team{
+			{ID: 1, Name: "Norway", Legs: []int{11, 12}},
+			{ID: 2, Name: "Sweden", Legs: []int{21, 22}},
+		},
+	}
+
+	comps := map[int]*competition.Competitor{
+		11: {ID: 11, TeamId: 1, Leg: 1, Started: true, Finished: true, FinishedAt: mustParse(t, "00:01:00.000"),
+			LapTimes: []time.Duration{mustParse(t, "00:01:00.000")}, Hits: 5, Shots: 5},
+		12: {ID: 12, TeamId: 1, Leg: 2, Started: true, Finished: true, FinishedAt: mustParse(t, "00:02:40.000"),
+			LapTimes: []time.Duration{mustParse(t, "00:01:40.000")}, Hits: 4, Shots: 5, Spares: 2},
+		21: {ID: 21, TeamId: 2, Leg: 1, Started: true, NotFinished: true, Hits: 0, Shots: 0},
+	}
+
+	lines := report.GenerateRelayReport(cfg, comps)
+
+	want := []string{
+		"1. 00:02:40.000 +00:00:00.000 +00:00:00.000 1 Norway",
+		"  1: 00:01:00.000 11 [{00:01:00.000, 16.667}] {,} 5/5+0",
+		"  2: 00:01:40.000 12 [{00:01:40.000, 10.000}] {,} 4/5+2",
+		"[NotFinished] 2 Sweden",
+		"  1: [NotFinished] 21 [{,}] {,} 0/0+0",
+		"  2: [NotReached] 22 [{,}] {,} 0/0+0",
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("got %d lines %q, want %d", len(lines), lines, len(want))
+	}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Errorf("line %d = %q; want %q", i, lines[i], want[i])
+		}
+	}
+}
+
+func TestGenerateReport_Athlete(t *testing.T) {
+	dir := t.TempDir()
+	origWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd: %v", err)
+	}
+	defer os.Chdir(origWd)
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+
+	cfg := &config.Config{Laps: 1, LapLen: 1000}
+	comps := map[int]*competition.Competitor{
+		1: {ID: 1, Started: true, Finished: true,
+			Athlete:  &registry.Athlete{ID: 1, Name: "Johannes Boe", Bib: 7, Nation: "NOR"},
+			LapTimes: []time.Duration{mustParse(t, "00:01:00.000")}, Hits: 5, Shots: 5},
+	}
+
+	lines := report.GenerateReport(cfg, comps)
+	want := "1. 00:01:00.000 +00:00:00.000 +00:00:00.000 1 #7 Johannes Boe (NOR) [{00:01:00.000, 16.667}] {,} 5/5"
+	if len(lines) != 1 || lines[0] != want {
+		t.Errorf("got %q; want %q", lines, want)
+	}
+	rows, err := report.ParseResults(strings.NewReader(strings.Join(lines, "\n")))
+	if err != nil || len(rows) != 1 || rows[0].ID != 1 {
+		t.Errorf("ParseResults = %+v, %v", rows, err)
+	}
+}
+
+func TestGenerateReport_Categories(t *testing.T) {
+	dir := t.TempDir()
+	origWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd: %v", err)
 	}
-	gotFile := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
-	if len(gotFile) != len(want) {
-		t.Fatalf("file has %d lines; want %d", len(gotFile), len(want))
+	defer os.Chdir(origWd)
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+
+	cfg := &config.Config{
+		Laps: 2, LapLen: 1000,
+		Categories: map[string]config.Category{"Junior": {Laps: 1, LapLen: 500}},
+	}
+	athlete := func(id int, cat string) *registry.Athlete {
+		return &registry.Athlete{ID: id, Name: fmt.Sprint("A", id), Category: cat}
+	}
+	lap := mustParse(t, "00:01:00.000")
+	comps := map[int]*competition.Competitor{
+		1: {ID: 1, Started: true, Finished: true, Athlete: athlete(1, "Senior"),
+			LapTimes: []time.Duration{lap, lap}, Hits: 10, Shots: 10},
+		2: {ID: 2, Started: true, Finished: true, Athlete: athlete(2, "Senior"),
+			LapTimes: []time.Duration{lap, 2 * lap}, Hits: 10, Shots: 10},
+		3: {ID: 3, Started: true, Finished: true, Athlete: athlete(3, "Junior"),
+			LapTimes: []time.Duration{3 * lap}, Hits: 5, Shots: 5},
+		4: {ID: 4, Started: true, NotFinished: true, Athlete: athlete(4, "Junior")},
+		5: {ID: 5, Started: true, Finished: true, LapTimes: []time.Duration{lap, lap}},
+	}
+
+	lines := report.GenerateReport(cfg, comps)
+	want := []string{
+		"== Junior ==",
+		"1. 00:03:00.000 +00:00:00.000 +00:00:00.000 3 A3 (Junior) [{00:03:00.000, 2.778}] {,} 5/5",
+		"[NotFinished] 4 A4 (Junior) [{,}] {,} 0/0",
+		"== Senior ==",
+		"1. 00:02:00.000 +00:00:00.000 +00:00:00.000 1 A1 (Senior) [{00:01:00.000, 16.667}, {00:01:00.000, 16.667}] {,} 10/10",
+		"2. 00:03:00.000 +00:01:00.000 +00:01:00.000 2 A2 (Senior) [{00:01:00.000, 16.667}, {00:02:00.000, 8.333}] {,} 10/10",
+		"== Uncategorized ==",
+		"1. 00:02:00.000 +00:00:00.000 +00:00:00.000 5 [{00:01:00.000, 16.667}, {00:01:00.000, 16.667}] {,} 0/0",
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("got %d lines %q, want %d", len(lines), lines, len(want))
 	}
 	for i := range want {
-		if gotFile[i] != want[i] {
-			t.Errorf("file line %d = %q; want %q", i, gotFile[i], want[i])
+		if lines[i] != want[i] {
+			t.Errorf("line %d = %q; want %q", i, lines[i], want[i])
+		}
+	}
+}
+
+func TestNewResult(t *testing.T) {
+	cfg := &config.Config{Laps: 2, LapLen: 1000, PenaltyLen: 100}
+	comps := map[int]*competition.Competitor{
+		1: {ID: 1, Started: true, Finished: true,
+			LapTimes:     []time.Duration{mustParse(t, "00:01:00.000"), mustParse(t, "00:02:00.000")},
+			PenaltyCount: 1, PenaltyTime: mustParse(t, "00:00:20.000"),
+			PenaltyVisits: []competition.PenaltyVisit{{Loops: 1}},
+			Hits:          9, Shots: 10},
+		2: {ID: 2, Started: true, NotFinished: true, ActualStart: mustParse(t, "00:00:10.000"),
+			LapTimes: []time.Duration{mustParse(t, "00:01:30.000")}},
+	}
+
+	res := report.NewResult(cfg, comps)
+	if len(res.Rows) != 2 || len(res.Teams) != 0 {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	r := res.Rows[0]
+	if r.ID != 1 || r.Place != 1 || r.Status != report.StatusFinished ||
+		r.Total != mustParse(t, "00:03:20.000") || r.Hits != 9 || r.Shots != 10 {
+		t.Errorf("row 0 = %+v", r)
+	}
+	if len(r.Laps) != 2 || r.Laps[1].Time != mustParse(t, "00:02:00.000") || r.Laps[1].Speed != 1000.0/120 {
+		t.Errorf("laps = %+v", r.Laps)
+	}
+	if r.Penalty == nil || r.PenaltyLoops != 1 || r.Penalty.Speed != 5 {
+		t.Errorf("penalty = %+v, loops %d", r.Penalty, r.PenaltyLoops)
+	}
+	if r := res.Rows[1]; r.Status != report.StatusNotFinished || r.Place != 0 || r.Laps[1] != nil {
+		t.Errorf("row 1 = %+v", r)
+	}
+
+	var sb strings.Builder
+	var rnd report.Renderer = report.TextRenderer{}
+	if err := rnd.Render(&sb, res); err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	want := "1. 00:03:20.000 +00:00:00.000 +00:00:00.000 1 [{00:01:00.000, 16.667}, {00:02:00.000, 8.333}] {00:00:20.000, 5.000} 9/10\n" +
+		"[NotFinished] 2 [{00:00:10.000, 100.000}, {,}] {,} 0/0\n"
+	if sb.String() != want {
+		t.Errorf("Render =\n%s\nwant\n%s", sb.String(), want)
+	}
+}
+
+func TestJSONRenderer(t *testing.T) {
+	cfg := &config.Config{Laps: 1, LapLen: 1000, PenaltyLen: 100, Start: mustParse(t, "10:00:00.000")}
+	comps := map[int]*competition.Competitor{
+		1: {ID: 1, Started: true, Finished: true, FinishedAt: mustParse(t, "10:01:00.000"),
+			LapTimes:       []time.Duration{mustParse(t, "00:01:00.000")},
+			Athlete:        &registry.Athlete{ID: 1, Name: "Johannes Boe", Nation: "NOR"},
+			Hits:           5,
+			Shots:          5,
+			Bouts:          []competition.Bout{{Range: 1, Lap: 1, Shots: 5, Targets: []int{1, 2, 3, 4, 5}}},
+			OutgoingEvents: []competition.OutgoingEvent{{Time: mustParse(t, "10:01:00.000"), EventId: 33}}},
+		2: {ID: 2, NotStarted: true,
+			OutgoingEvents: []competition.OutgoingEvent{{Time: mustParse(t, "10:00:30.000"), EventId: 32}}},
+	}
+
+	var sb strings.Builder
+	if err := (report.JSONRenderer{}).Render(&sb, report.NewResult(cfg, comps)); err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+
+	var doc struct {
+		SchemaVersion int
+		Race          struct{ Format, Start string }
+		Results       []struct {
+			Place   int
+			ID      int
+			Athlete *struct{ Name string }
+			Status  string
+			Total   *string
+			Laps    []*struct {
+				Time  string
+				Speed float64
+			}
+			Shooting struct {
+				Hits  int
+				Bouts []struct{ Range, Hits int }
+			}
+			Events []struct {
+				Time    string
+				EventId int
+			}
+		}
+	}
+	if err := json.Unmarshal([]byte(sb.String()), &doc); err != nil {
+		t.Fatalf("invalid JSON %s: %v", sb.String(), err)
+	}
+	if doc.SchemaVersion != report.JSONSchemaVersion || doc.Race.Format != "interval" || doc.Race.Start != "10:00:00.000" {
+		t.Errorf("header = %+v", doc)
+	}
+	if len(doc.Results) != 2 {
+		t.Fatalf("got %d results", len(doc.Results))
+	}
+	r := doc.Results[0]
+	if r.Place != 1 || r.ID != 1 || r.Athlete == nil || r.Athlete.Name != "Johannes Boe" ||
+		r.Status != "Finished" || r.Total == nil || *r.Total != "00:01:00.000" {
+		t.Errorf("row 0 = %+v", r)
+	}
+	if len(r.Laps) != 1 || r.Laps[0].Speed != 16.667 || r.Shooting.Hits != 5 || len(r.Shooting.Bouts) != 1 ||
+		r.Shooting.Bouts[0].Hits != 5 || len(r.Events) != 1 || r.Events[0].EventId != 33 {
+		t.Errorf("row 0 details = %+v", r)
+	}
+	if r := doc.Results[1]; r.Status != "NotStarted" || r.Total != nil || r.Place != 0 || r.Laps[0] != nil ||
+		r.Events[0].Time != "10:00:30.000" {
+		t.Errorf("row 1 = %+v", r)
+	}
+}
+
+func TestCSVRenderer(t *testing.T) {
+	cfg := &config.Config{Laps: 2, LapLen: 1000, PenaltyLen: 100}
+	comps := map[int]*competition.Competitor{
+		1: {ID: 1, Started: true, Finished: true,
+			Athlete:      &registry.Athlete{ID: 1, Name: "Boe, Johannes", Bib: 7, Nation: "NOR", Club: "Ski Team", Gender: "M"},
+			LapTimes:     []time.Duration{mustParse(t, "00:01:00.000"), mustParse(t, "00:02:00.000")},
+			PenaltyTime:  mustParse(t, "00:00:20.000"),
+			PenaltyCount: 1, PenaltyVisits: []competition.PenaltyVisit{{Loops: 1}},
+			Hits: 9, Shots: 10},
+		2: {ID: 2, NotStarted: true},
+	}
+	res := report.NewResult(cfg, comps)
+
+	var sb strings.Builder
+	if err := (report.CSVRenderer{Header: true}).Render(&sb, res); err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	want := "place,id,bib,name,nation,club,gender,category,status,total,gap,behind,lap1_time,lap1_speed,lap2_time,lap2_speed," +
+		"penalty_time,penalty_speed,penalty_loops,hits,shots,spares,miss_penalty\n" +
+		`1,1,7,"Boe, Johannes",NOR,Ski Team,M,,Finished,00:03:20.000,00:00:00.000,00:00:00.000,00:01:00.000,16.667,00:02:00.000,8.333,` +
+		"00:00:20.000,5.000,1,9,10,0,\n" +
+		",2,,,,,,,NotStarted,,,,,,,,,,0,0,0,0,\n"
+	if sb.String() != want {
+		t.Errorf("CSV =\n%s\nwant\n%s", sb.String(), want)
+	}
+
+	sb.Reset()
+	if err := (report.CSVRenderer{Comma: ';'}).Render(&sb, res); err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if lines := strings.Split(sb.String(), "\n"); len(lines) != 3 || !strings.HasPrefix(lines[0], "1;1;7;Boe, Johannes;NOR;Ski Team;M;") {
+		t.Errorf("CSV without header = %q", sb.String())
+	}
+}
+
+func TestHTMLRenderer(t *testing.T) {
+	cfg := &config.Config{
+		Laps: 1, LapLen: 1000, Start: mustParse(t, "10:00:00.000"),
+		Format: config.FormatRelay,
+		Teams: []config.Team{
+			{ID: 1, Name: "Norway <NOR>", Legs: []int{11}},
+			{ID: 2, Name: "Empty"},
+		},
+	}
+	comps := map[int]*competition.Competitor{
+		11: {ID: 11, TeamId: 1, Leg: 1, Started: true, NotFinished: true,
+			Athlete: &registry.Athlete{ID: 11, Name: "Sturla Laegreid", Bib: 7, Nation: "NOR", Club: "Ski Team", Gender: "M"}},
+	}
+
+	var sb strings.Builder
+	if err := (report.HTMLRenderer{Title: "Relay"}).Render(&sb, report.NewResult(cfg, comps)); err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	page := sb.String()
+	for _, want := range []string{
+		"<title>Relay</title>",
+		"<style>",
+		"Lap length: <b>1000 m</b>",
+		"Start: <b>10:00:00.000</b>",
+		"Norway &lt;NOR&gt;",
+		`<span class="badge NotFinished">NotFinished</span>`,
+		"<th>Leg</th>",
+		"<th>Lap 1</th>",
+		"<th>Bib</th>",
+		`<td class="num" data-order="7">7</td>`,
+		"<td>Sturla Laegreid</td>",
+		"<td>Ski Team</td>",
+		"<td>M</td>",
+		`table.querySelectorAll("th")`,
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page missing %q", want)
+		}
+	}
+	for _, external := range []string{"<link", " src=", "http://", "https://"} {
+		if strings.Contains(page, external) {
+			t.Errorf("page references external asset %q", external)
+		}
+	}
+}
+
+func TestXLSXRenderer(t *testing.T) {
+	cfg := &config.Config{Laps: 1, LapLen: 1000}
+	comps := map[int]*competition.Competitor{
+		1: {ID: 1, Started: true, Finished: true,
+			Athlete:  &registry.Athlete{ID: 1, Name: "Boe & Co", Bib: 7, Club: "Ski Team", Gender: "M"},
+			LapTimes: []time.Duration{mustParse(t, "00:12:00.000")},
+			Hits:     4, Shots: 5,
+			Bouts: []competition.Bout{{Range: 1, Lap: 1, Shots: 5, Targets: []int{1, 2, 3, 4},
+				EnteredAt: mustParse(t, "10:05:00.000"), LeftAt: mustParse(t, "10:05:30.000")}}},
+	}
+
+	var buf bytes.Buffer
+	if err := (report.XLSXRenderer{}).Render(&buf, report.NewResult(cfg, comps)); err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("not a zip: %v", err)
+	}
+	parts := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		parts[f.Name] = string(data)
+	}
+
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/styles.xml",
+		"xl/worksheets/sheet1.xml", "xl/worksheets/sheet2.xml", "xl/worksheets/sheet3.xml"} {
+		if _, ok := parts[name]; !ok {
+			t.Errorf("missing part %s", name)
+		}
+	}
+	for _, sheet := range []string{`name="Results"`, `name="Lap Times"`, `name="Shooting"`} {
+		if !strings.Contains(parts["xl/workbook.xml"], sheet) {
+			t.Errorf("workbook missing sheet %s", sheet)
+		}
+	}
+	// 12 минут = 1/120 суток, стиль 1 — формат длительности
+	if !strings.Contains(parts["xl/worksheets/sheet1.xml"], `<c r="J2" s="1"><v>0.008333333333333333</v></c>`) {
+		t.Errorf("total is not stored as a time value:\n%s", parts["xl/worksheets/sheet1.xml"])
+	}
+	if !strings.Contains(parts["xl/worksheets/sheet1.xml"], "<t>Boe &amp; Co</t>") {
+		t.Error("athlete name not escaped")
+	}
+	for _, cell := range []string{`<c r="C2"><v>7</v></c>`, "<t>Ski Team</t>", "<t>M</t>"} {
+		if !strings.Contains(parts["xl/worksheets/sheet1.xml"], cell) {
+			t.Errorf("results sheet missing athlete detail %s", cell)
+		}
+	}
+	if !strings.Contains(parts["xl/worksheets/sheet3.xml"], `<c r="J2"><v>1</v></c>`) {
+		t.Errorf("shooting sheet missing misses:\n%s", parts["xl/worksheets/sheet3.xml"])
+	}
+	for name, body := range parts {
+		if err := xml.Unmarshal([]byte(body), new(struct{})); err != nil {
+			t.Errorf("%s is not well-formed XML: %v", name, err)
 		}
 	}
 }
@@ -0,0 +1,213 @@
+package report
+
+import (
+	"time"
+
+	"github.com/ironywer/sunny_5_skiers/competition"
+	"github.com/ironywer/sunny_5_skiers/config"
+	"github.com/ironywer/sunny_5_skiers/registry"
+)
+
+// Status — итоговое состояние участника или команды в протоколе.
+type Status string
+
+const (
+	StatusFinished    Status = "Finished"
+	StatusRacing      Status = "Racing" // ещё на трассе или не стартовал к моменту отчёта
+	StatusNotFinished Status = "NotFinished"
+	StatusNotStarted  Status = "NotStarted"
+	StatusNotReached  Status = "NotReached" // этап эстафеты: команда сошла до передачи
+)
+
+// Lap — время и средняя скорость круга (или штрафных кругов).
+type Lap struct {
+	Time  time.Duration
+	Speed float64 // м/с
+}
+
+// ResultRow — строка протокола одного участника.
+type ResultRow struct {
+	Place    int // 0 — без места
+	ID       int
+	Athlete  *registry.Athlete // nil, если участника нет в реестре
+	Category string
+	Leg      int // этап эстафеты, 0 — не эстафета
+	Status   Status
+	Total    time.Duration // итоговое время; для Racing — текущее
+	Gap      time.Duration // отставание от лидера
+	Behind   time.Duration // отставание от предыдущего
+	// Laps — по элементу на каждый круг дистанции; nil — круг не пройден.
+	Laps []*Lap
+	// Penalty — время и скорость на штрафных кругах, nil — не было.
+	Penalty      *Lap
+	PenaltyLoops int
+	Hits         int
+	Shots        int
+	Spares       int
+	MissPenalty  time.Duration // штрафное время за промахи (scoring: time)
+	Bouts        []competition.Bout
+	// Outgoing — сгенерированные события участника (32, 33).
+	Outgoing []competition.OutgoingEvent
+}
+
+// TeamRow — строка протокола эстафеты.
+type TeamRow struct {
+	Place  int
+	ID     int
+	Name   string
+	Status Status
+	Total  time.Duration
+	Gap    time.Duration
+	Behind time.Duration
+	Legs   []ResultRow
+}
+
+// Result — итоговый протокол гонки, не зависящий от формата вывода.
+type Result struct {
+	Config *config.Config
+	// ByCategory — протокол разбит по категориям: Rows идут группами
+	// по Category, места считаются внутри категории.
+	ByCategory bool
+	Rows       []ResultRow
+	Teams      []TeamRow // только для эстафеты
+}
+
+// NewResult строит протокол по правилам cfg.Format: для эстафеты
+// заполняется Teams, для остальных гонок — Rows.
+func NewResult(cfg *config.Config, comps map[int]*competition.Competitor) *Result {
+	if cfg.Format == config.FormatRelay {
+		return relayResult(cfg, comps)
+	}
+	return individualResult(cfg, comps)
+}
+
+func individualResult(cfg *config.Config, comps map[int]*competition.Competitor) *Result {
+	res := &Result{Config: cfg, ByCategory: hasCategories(comps)}
+	if !res.ByCategory {
+		res.Rows = rankedRows(cfg, competition.Ranking(cfg, comps))
+		return res
+	}
+	for _, g := range competition.RankingByCategory(cfg, comps) {
+		res.Rows = append(res.Rows, rankedRows(cfg, g.Competitors)...)
+	}
+	return res
+}
+
+func relayResult(cfg *config.Config, comps map[int]*competition.Competitor) *Result {
+	res := &Result{Config: cfg}
+	teams := competition.TeamRanking(cfg, comps)
+	places := competition.TeamPlacings(teams)
+	for i, t := range teams {
+		tr := TeamRow{
+			Place:  places[i].Place,
+			ID:     t.ID,
+			Name:   t.Name,
+			Gap:    places[i].Gap,
+			Behind: places[i].Behind,
+		}
+		switch {
+		case t.NotStarted():
+			tr.Status = StatusNotStarted
+		case t.NotFinished():
+			tr.Status = StatusNotFinished
+		case t.Finished():
+			tr.Status = StatusFinished
+			tr.Total = t.TotalTime()
+		default:
+			tr.Status = StatusRacing
+			tr.Total = t.TotalTime()
+		}
+		for _, c := range t.Legs {
+			tr.Legs = append(tr.Legs, newRow(cfg, c))
+		}
+		res.Teams = append(res.Teams, tr)
+	}
+	return res
+}
+
+func rankedRows(cfg *config.Config, ranked []*competition.Competitor) []ResultRow {
+	rows := make([]ResultRow, len(ranked))
+	places := competition.Placings(cfg, ranked)
+	for i, c := range ranked {
+		rows[i] = newRow(cfg, c)
+		rows[i].Place = places[i].Place
+		rows[i].Gap = places[i].Gap
+		rows[i].Behind = places[i].Behind
+	}
+	return rows
+}
+
+func newRow(cfg *config.Config, c *competition.Competitor) ResultRow {
+	cfg = cfg.ForCategory(c.Category())
+	r := ResultRow{
+		ID:           c.ID,
+		Athlete:      c.Athlete,
+		Category:     c.Category(),
+		Leg:          c.Leg,
+		PenaltyLoops: c.PenaltyLoops(),
+		Hits:         c.Hits,
+		Shots:        c.Shots,
+		Spares:       c.Spares,
+		MissPenalty:  c.MissPenalty,
+		Bouts:        c.Bouts,
+		Outgoing:     c.OutgoingEvents,
+	}
+	deltaStart := c.ActualStart - c.ScheduledAt
+
+	switch {
+	case c.NotReached:
+		r.Status = StatusNotReached
+	case c.NotStarted:
+		r.Status = StatusNotStarted
+	case c.NotFinished:
+		r.Status = StatusNotFinished
+	case c.Finished:
+		r.Status = StatusFinished
+		r.Total = c.TotalTime()
+	default:
+		r.Status = StatusRacing
+		if c.Started {
+			r.Total = c.TotalTime()
+		}
+	}
+
+	r.Laps = make([]*Lap, cfg.Laps)
+	for i := 0; i < cfg.Laps && i < len(c.LapTimes); i++ {
+		lt := c.LapTimes[i]
+		if r.Status == StatusNotFinished && i == len(c.LapTimes)-1 || r.Status == StatusNotStarted {
+			lt = deltaStart
+		}
+		r.Laps[i] = &Lap{Time: lt, Speed: float64(cfg.LapLen) / lt.Seconds()}
+	}
+
+	// скорость — по числу заходов: круги, выведенные из времени
+	// на PenaltyLoopSpeed, дали бы саму PenaltyLoopSpeed
+	if c.PenaltyCount > 0 && c.PenaltyTime > 0 {
+		r.Penalty = &Lap{
+			Time:  c.PenaltyTime,
+			Speed: float64(cfg.PenaltyLen*c.PenaltyCount) / c.PenaltyTime.Seconds(),
+		}
+	}
+	return r
+}
+
+// RowTimes — total, gap и behind строкой; пусто, если у строки их нет:
+// total есть у Finished и Racing, отставания — только у строк с местом.
+func RowTimes(place int, status Status, total, gap, behind time.Duration) (t, g, b string) {
+	if status == StatusFinished || status == StatusRacing {
+		t = format(total)
+	}
+	if place > 0 {
+		g, b = format(gap), format(behind)
+	}
+	return t, g, b
+}
+
+func hasCategories(comps map[int]*competition.Competitor) bool {
+	for _, c := range comps {
+		if c.Category() != "" {
+			return true
+		}
+	}
+	return false
+}
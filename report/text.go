@@ -0,0 +1,90 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ironywer/sunny_5_skiers/config"
+)
+
+// TextRenderer выводит протокол в текстовом формате resulting_table:
+// строка на участника, для эстафеты — строка команды и строки этапов.
+type TextRenderer struct{}
+
+func (t TextRenderer) Render(w io.Writer, res *Result) error {
+	for _, line := range t.Lines(res) {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Lines возвращает строки текстового протокола без перевода строки.
+func (TextRenderer) Lines(res *Result) []string {
+	var out []string
+	if res.Config.Format == config.FormatRelay {
+		for _, t := range res.Teams {
+			out = append(out, teamLine(t))
+			for _, r := range t.Legs {
+				out = append(out, fmt.Sprintf("  %d: %s", r.Leg, rowLine(res, r)))
+			}
+		}
+		return out
+	}
+	for i, r := range res.Rows {
+		if res.ByCategory && (i == 0 || res.Rows[i-1].Category != r.Category) {
+			out = append(out, categoryHeader(r.Category))
+		}
+		out = append(out, rowLine(res, r))
+	}
+	return out
+}
+
+// first — первая колонка: "место. время +от_лидера +от_предыдущего",
+// время или статус в квадратных скобках.
+func first(place int, status Status, total, gap, behind time.Duration) string {
+	switch status {
+	case StatusNotStarted, StatusNotFinished, StatusNotReached:
+		return fmt.Sprintf("[%s]", status)
+	}
+	if place == 0 {
+		return format(total)
+	}
+	return fmt.Sprintf("%d. %s +%s +%s", place, format(total), format(gap), format(behind))
+}
+
+func teamLine(t TeamRow) string {
+	return fmt.Sprintf("%s %d %s", first(t.Place, t.Status, t.Total, t.Gap, t.Behind), t.ID, t.Name)
+}
+
+func rowLine(res *Result, r ResultRow) string {
+	var athlete string
+	if r.Athlete != nil {
+		athlete = " " + r.Athlete.Details()
+	}
+
+	laps := make([]string, len(r.Laps))
+	for i, l := range r.Laps {
+		laps[i] = lapString(l)
+	}
+
+	shots := fmt.Sprintf("%d/%d", r.Hits, r.Shots)
+	if res.Config.SparesPerBout() > 0 {
+		shots += fmt.Sprintf("+%d", r.Spares)
+	}
+	if res.Config.TimeScoring() {
+		shots += " +" + format(r.MissPenalty)
+	}
+	return fmt.Sprintf("%s %d%s [%s] %s %s", first(r.Place, r.Status, r.Total, r.Gap, r.Behind),
+		r.ID, athlete, strings.Join(laps, ", "), lapString(r.Penalty), shots)
+}
+
+func lapString(l *Lap) string {
+	if l == nil {
+		return "{,}"
+	}
+	return fmt.Sprintf("{%s, %.3f}", format(l.Time), l.Speed)
+}
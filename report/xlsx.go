@@ -0,0 +1,324 @@
+package report
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ironywer/sunny_5_skiers/config"
+	"github.com/ironywer/sunny_5_skiers/registry"
+)
+
+// XLSXRenderer выводит протокол книгой Excel из трёх листов: Results,
+// Lap Times и Shooting. Время записывается числом (доля суток) с форматом
+// времени, поэтому в ячейках работают формулы и сортировка.
+type XLSXRenderer struct{}
+
+func (XLSXRenderer) Render(w io.Writer, res *Result) error {
+	relay := res.Config.Format == config.FormatRelay
+	rows := flatRows(res)
+	sheets := []xlsxSheet{
+		resultsSheet(rows, relay),
+		lapsSheet(rows, maxLaps(res.Config)),
+		shootingSheet(rows),
+	}
+	return writeXLSX(w, sheets)
+}
+
+// flatRow — строка участника; в эстафете вместе с командой.
+type flatRow struct {
+	ResultRow
+	Team *TeamRow
+}
+
+func flatRows(res *Result) []flatRow {
+	var out []flatRow
+	for _, r := range res.Rows {
+		out = append(out, flatRow{ResultRow: r})
+	}
+	for i := range res.Teams {
+		t := &res.Teams[i]
+		for _, r := range t.Legs {
+			out = append(out, flatRow{ResultRow: r, Team: t})
+		}
+	}
+	return out
+}
+
+func resultsSheet(rows []flatRow, relay bool) xlsxSheet {
+	sh := xlsxSheet{Name: "Results"}
+	var head []string
+	if relay {
+		head = append(head, "Team Place", "Team", "Team Status", "Team Total", "Leg")
+	}
+	head = append(head, "Place", "ID", "Bib", "Name", "Nation", "Club", "Gender", "Category",
+		"Status", "Total", "Gap", "Behind", "Penalty", "Penalty Loops", "Hits", "Shots", "Spares", "Miss Penalty")
+	sh.header(head...)
+
+	for _, r := range rows {
+		var row []xlsxCell
+		if relay {
+			total, _, _ := RowTimes(r.Team.Place, r.Team.Status, r.Team.Total, 0, 0)
+			row = append(row, intCell(r.Team.Place), strCell(r.Team.Name), strCell(string(r.Team.Status)),
+				optDuration(total != "", r.Team.Total), numCell(float64(r.Leg)))
+		}
+		var a registry.Athlete
+		if r.Athlete != nil {
+			a = *r.Athlete
+		}
+		total, gap, _ := RowTimes(r.Place, r.Status, r.Total, r.Gap, r.Behind)
+		var penalty xlsxCell
+		if r.Penalty != nil {
+			penalty = durationCell(r.Penalty.Time)
+		}
+		row = append(row,
+			intCell(r.Place), numCell(float64(r.ID)), intCell(a.Bib), strCell(a.Name), strCell(a.Nation),
+			strCell(a.Club), strCell(a.Gender), strCell(r.Category), strCell(string(r.Status)),
+			optDuration(total != "", r.Total), optDuration(gap != "", r.Gap), optDuration(gap != "", r.Behind),
+			penalty, numCell(float64(r.PenaltyLoops)),
+			numCell(float64(r.Hits)), numCell(float64(r.Shots)), numCell(float64(r.Spares)),
+			optDuration(r.MissPenalty > 0, r.MissPenalty))
+		sh.Rows = append(sh.Rows, row)
+	}
+	return sh
+}
+
+func lapsSheet(rows []flatRow, laps int) xlsxSheet {
+	sh := xlsxSheet{Name: "Lap Times"}
+	head := []string{"ID", "Name"}
+	for i := 1; i <= laps; i++ {
+		head = append(head, fmt.Sprintf("Lap %d", i), fmt.Sprintf("Lap %d Speed", i))
+	}
+	sh.header(head...)
+	for _, r := range rows {
+		row := []xlsxCell{numCell(float64(r.ID)), strCell(athleteName(r.ResultRow))}
+		for i := 0; i < laps; i++ {
+			if i < len(r.Laps) && r.Laps[i] != nil {
+				row = append(row, durationCell(r.Laps[i].Time), speedCell(r.Laps[i].Speed))
+			} else {
+				row = append(row, xlsxCell{}, xlsxCell{})
+			}
+		}
+		sh.Rows = append(sh.Rows, row)
+	}
+	return sh
+}
+
+func shootingSheet(rows []flatRow) xlsxSheet {
+	sh := xlsxSheet{Name: "Shooting"}
+	sh.header("ID", "Name", "Range", "Lap", "Entered", "Left", "Hits", "Shots", "Spares", "Misses")
+	for _, r := range rows {
+		for _, b := range r.Bouts {
+			sh.Rows = append(sh.Rows, []xlsxCell{
+				numCell(float64(r.ID)), strCell(athleteName(r.ResultRow)),
+				numCell(float64(b.Range)), numCell(float64(b.Lap)),
+				clockCell(b.EnteredAt), optClock(b.LeftAt > 0, b.LeftAt),
+				numCell(float64(b.Hits())), numCell(float64(b.Shots)), numCell(float64(b.Spares)),
+				numCell(float64(b.Misses())),
+			})
+		}
+	}
+	return sh
+}
+
+func athleteName(r ResultRow) string {
+	if r.Athlete == nil {
+		return ""
+	}
+	return r.Athlete.Name
+}
+
+// Стили ячеек — индексы cellXfs в styles.xml.
+const (
+	styleDefault = iota
+	styleDuration
+	styleClock
+	styleSpeed
+	styleHeader
+)
+
+// xlsxCell — ячейка листа: пустая, строка или число со стилем.
+type xlsxCell struct {
+	kind  byte // 0 — пусто, 's' — строка, 'n' — число
+	str   string
+	num   float64
+	style int
+}
+
+func strCell(s string) xlsxCell {
+	if s == "" {
+		return xlsxCell{}
+	}
+	return xlsxCell{kind: 's', str: s}
+}
+
+func numCell(f float64) xlsxCell {
+	return xlsxCell{kind: 'n', num: f}
+}
+
+func intCell(n int) xlsxCell {
+	if n == 0 {
+		return xlsxCell{}
+	}
+	return numCell(float64(n))
+}
+
+func speedCell(f float64) xlsxCell {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return xlsxCell{}
+	}
+	return xlsxCell{kind: 'n', num: f, style: styleSpeed}
+}
+
+// durationCell хранит длительность в сутках — так Excel представляет время.
+func durationCell(d time.Duration) xlsxCell {
+	return xlsxCell{kind: 'n', num: d.Seconds() / 86400, style: styleDuration}
+}
+
+func clockCell(d time.Duration) xlsxCell {
+	return xlsxCell{kind: 'n', num: d.Seconds() / 86400, style: styleClock}
+}
+
+func optDuration(ok bool, d time.Duration) xlsxCell {
+	if !ok {
+		return xlsxCell{}
+	}
+	return durationCell(d)
+}
+
+func optClock(ok bool, d time.Duration) xlsxCell {
+	if !ok {
+		return xlsxCell{}
+	}
+	return clockCell(d)
+}
+
+type xlsxSheet struct {
+	Name string
+	Rows [][]xlsxCell
+}
+
+func (sh *xlsxSheet) header(names ...string) {
+	row := make([]xlsxCell, len(names))
+	for i, n := range names {
+		row[i] = xlsxCell{kind: 's', str: n, style: styleHeader}
+	}
+	sh.Rows = append(sh.Rows, row)
+}
+
+// writeXLSX собирает минимальный пакет SpreadsheetML: строки хранятся
+// inline, без таблицы общих строк.
+func writeXLSX(w io.Writer, sheets []xlsxSheet) error {
+	zw := zip.NewWriter(w)
+	var types, rels, list strings.Builder
+	for i, sh := range sheets {
+		n := i + 1
+		fmt.Fprintf(&types, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, n)
+		fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, n, n)
+		fmt.Fprintf(&list, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, xmlEscape(sh.Name), n, n)
+	}
+	files := []struct{ name, body string }{
+		{"[Content_Types].xml", xmlHeader + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+			`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+			types.String() + `</Types>`},
+		{"_rels/.rels", xmlHeader + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`},
+		{"xl/workbook.xml", xmlHeader + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+			`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>` +
+			list.String() + `</sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", xmlHeader + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			rels.String() +
+			fmt.Sprintf(`<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, len(sheets)+1) +
+			`</Relationships>`},
+		{"xl/styles.xml", xlsxStyles},
+	}
+	for i, sh := range sheets {
+		files = append(files, struct{ name, body string }{
+			fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), sheetXML(sh),
+		})
+	}
+	for _, f := range files {
+		fw, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     f.name,
+			Method:   zip.Deflate,
+			Modified: xlsxModified,
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(fw, f.body); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// xlsxModified — постоянная дата файлов в архиве, чтобы книга для одного
+// протокола совпадала побайтно.
+var xlsxModified = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+const xmlHeader = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n"
+
+// xlsxStyles: 1 — длительность, 2 — время суток, 3 — скорость, 4 — заголовок.
+const xlsxStyles = xmlHeader + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<numFmts count="2"><numFmt numFmtId="164" formatCode="[h]:mm:ss.000"/><numFmt numFmtId="165" formatCode="hh:mm:ss.000"/></numFmts>` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="5">` +
+	`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="165" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="4" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+	`</cellXfs></styleSheet>`
+
+func sheetXML(sh xlsxSheet) string {
+	var b strings.Builder
+	b.WriteString(xmlHeader)
+	b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	for i, row := range sh.Rows {
+		fmt.Fprintf(&b, `<row r="%d">`, i+1)
+		for j, c := range row {
+			ref := fmt.Sprintf("%s%d", columnName(j), i+1)
+			var style string
+			if c.style != styleDefault {
+				style = fmt.Sprintf(` s="%d"`, c.style)
+			}
+			switch c.kind {
+			case 's':
+				fmt.Fprintf(&b, `<c r="%s" t="inlineStr"%s><is><t>%s</t></is></c>`, ref, style, xmlEscape(c.str))
+			case 'n':
+				fmt.Fprintf(&b, `<c r="%s"%s><v>%s</v></c>`, ref, style, strconv.FormatFloat(c.num, 'g', -1, 64))
+			}
+		}
+		b.WriteString(`</row>`)
+	}
+	b.WriteString(`</sheetData></worksheet>`)
+	return b.String()
+}
+
+// columnName возвращает буквенное имя колонки: 0 — A, 26 — AA.
+func columnName(i int) string {
+	var s string
+	for i++; i > 0; i = (i - 1) / 26 {
+		s = string(rune('A'+(i-1)%26)) + s
+	}
+	return s
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
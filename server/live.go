@@ -0,0 +1,169 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ironywer/sunny_5_skiers/event"
+	"github.com/ironywer/sunny_5_skiers/report"
+)
+
+// Типы сообщений живой ленты (поле event в SSE).
+const (
+	updateIncoming  = "incoming"  // обработанное входящее событие
+	updateOutgoing  = "outgoing"  // сгенерированное событие 32 или 33
+	updateStandings = "standings" // изменилось положение
+	updateResync    = "resync"    // пропущенное уже вытеснено: текущее положение целиком
+)
+
+// update — сообщение ленты. ID растут с 1 и не переиспользуются, поэтому
+// клиент может продолжить с Last-Event-ID после переподключения.
+type update struct {
+	ID   int
+	Type string
+	Data []byte
+}
+
+// heartbeat — период комментариев SSE, не дающих прокси закрыть соединение.
+var heartbeat = 15 * time.Second
+
+// publish добавляет сообщение в ленту и будит подписчиков. Вызывается под s.mu.
+func (s *Server) publish(typ string, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	if len(s.updates) >= historyLimit {
+		s.updates = s.updates[1:]
+		s.evicted++
+	}
+	s.updates = append(s.updates, update{ID: s.evicted + len(s.updates) + 1, Type: typ, Data: data})
+	if s.changed != nil {
+		close(s.changed)
+	}
+	s.changed = make(chan struct{})
+}
+
+// publishEvent отправляет в ленту событие из потока гонки.
+func (s *Server) publishEvent(ev event.Event) {
+	typ := updateIncoming
+	if isOutgoing(ev) {
+		typ = updateOutgoing
+	}
+	s.publish(typ, toJSON([]event.Event{ev})[0])
+}
+
+// standing — строка положения в ленте: участник или команда эстафеты.
+type standing struct {
+	Place  int           `json:"place,omitempty"`
+	ID     int           `json:"id"`
+	Status report.Status `json:"status"`
+	Total  string        `json:"total,omitempty"`
+	Gap    string        `json:"gap,omitempty"`
+}
+
+// publishStandings отправляет положение, если оно изменилось с прошлого раза.
+// Вызывается под s.mu после каждого применённого события.
+func (s *Server) publishStandings() {
+	data, err := s.standings()
+	if err != nil || bytes.Equal(data, s.lastStandings) {
+		return
+	}
+	s.lastStandings = data
+	s.publish(updateStandings, json.RawMessage(data))
+}
+
+// standings — текущее положение в JSON. Вызывается под s.mu.
+func (s *Server) standings() ([]byte, error) {
+	res := report.NewResult(s.cfg, s.race.Competitors())
+	list := make([]standing, 0, len(res.Rows)+len(res.Teams))
+	for _, r := range res.Rows {
+		list = append(list, newStanding(r.Place, r.ID, r.Status, r.Total, r.Gap))
+	}
+	for _, t := range res.Teams {
+		list = append(list, newStanding(t.Place, t.ID, t.Status, t.Total, t.Gap))
+	}
+	return json.Marshal(list)
+}
+
+func newStanding(place, id int, status report.Status, total, gap time.Duration) standing {
+	t, g, _ := report.RowTimes(place, status, total, gap, 0)
+	return standing{Place: place, ID: id, Status: status, Total: t, Gap: g}
+}
+
+// getLive — лента Server-Sent Events. Сначала отдаются сообщения после
+// Last-Event-ID (заголовок или параметр lastEventId), затем новые по мере
+// поступления. Если часть пропущенного уже вытеснена или Last-Event-ID
+// больше последнего ID (сервер перезапущен), сначала отдаётся resync
+// с текущим положением и ID последнего сообщения.
+func (s *Server) getLive(w http.ResponseWriter, r *http.Request) {
+	last := r.Header.Get("Last-Event-ID")
+	if last == "" {
+		last = r.URL.Query().Get("lastEventId")
+	}
+	var next int
+	if last != "" {
+		id, err := strconv.Atoi(last)
+		if err != nil || id < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("неверный Last-Event-ID: %s", last))
+			return
+		}
+		next = id
+	}
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	rc.Flush()
+
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+	for {
+		s.mu.Lock()
+		var pending []update
+		if latest := s.evicted + len(s.updates); next < s.evicted || next > latest {
+			data, err := s.standings()
+			if err != nil {
+				s.mu.Unlock()
+				return
+			}
+			pending = []update{{ID: latest, Type: updateResync, Data: data}}
+		} else {
+			pending = s.updates[next-s.evicted:]
+		}
+		changed := s.changed
+		if changed == nil {
+			s.changed = make(chan struct{})
+			changed = s.changed
+		}
+		s.mu.Unlock()
+
+		for _, u := range pending {
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", u.ID, u.Type, u.Data); err != nil {
+				return
+			}
+			next = u.ID
+		}
+		if len(pending) > 0 {
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+
+		select {
+		case <-changed:
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			rc.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,305 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ironywer/sunny_5_skiers/competition"
+	"github.com/ironywer/sunny_5_skiers/config"
+	"github.com/ironywer/sunny_5_skiers/event"
+	"github.com/ironywer/sunny_5_skiers/report"
+)
+
+// maxBody — предельный размер тела POST /events.
+const maxBody = 10 << 20
+
+// historyLimit — сколько последних событий и сообщений ленты хранит сервер.
+var historyLimit = 10000
+
+// Server принимает события гонки по HTTP и отдаёт текущее положение.
+// Все обращения к Race идут под одним мьютексом.
+type Server struct {
+	mu       sync.Mutex
+	cfg      *config.Config
+	race     *competition.Race
+	stream   []event.Event // последние historyLimit событий в порядке обработки
+	outgoing []event.Event // все исходящие события (32, 33): их не больше числа участников
+	dropped  map[int]bool  // участники, чьи события вытеснены из stream
+
+	updates       []update      // последние historyLimit сообщений живой ленты, см. getLive
+	evicted       int           // сколько сообщений ленты вытеснено: ID первого хранимого — evicted+1
+	changed       chan struct{} // закрывается при каждом новом сообщении ленты
+	lastStandings []byte
+}
+
+// New создаёт сервер над race. Поток событий race перенаправляется
+// в сервер, поэтому SetOutput после New вызывать не нужно.
+func New(cfg *config.Config, race *competition.Race) *Server {
+	s := &Server{cfg: cfg, race: race, dropped: make(map[int]bool)}
+	race.SetOutput(sinkFunc(s.record))
+	return s
+}
+
+type sinkFunc func(event.Event) error
+
+func (f sinkFunc) WriteEvent(ev event.Event) error {
+	return f(ev)
+}
+
+// record вызывается из Race под s.mu.
+func (s *Server) record(ev event.Event) error {
+	if len(s.stream) >= historyLimit {
+		s.dropped[s.stream[0].CompetitorId] = true
+		s.stream = s.stream[1:]
+	}
+	s.stream = append(s.stream, ev)
+	if isOutgoing(ev) {
+		s.outgoing = append(s.outgoing, ev)
+	}
+	s.publishEvent(ev)
+	return nil
+}
+
+// Handler возвращает маршруты API:
+//
+//	POST /events                         события строками входного файла или JSON (объект или массив)
+//	POST /finalize                       завершить гонку: дисквалифицировать не стартовавших
+//	GET  /standings?format=json          текущий протокол (json, text, csv, html, xlsx)
+//	GET  /competitors/{id}/timeline      события участника
+//	GET  /events/outgoing                исходящие события (32, 33)
+//	GET  /live                           лента Server-Sent Events: события и изменения положения
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /events", s.postEvents)
+	mux.HandleFunc("POST /finalize", s.postFinalize)
+	mux.HandleFunc("GET /standings", s.getStandings)
+	mux.HandleFunc("GET /competitors/{id}/timeline", s.getTimeline)
+	mux.HandleFunc("GET /events/outgoing", s.getOutgoing)
+	mux.HandleFunc("GET /live", s.getLive)
+	return mux
+}
+
+// Apply применяет события под мьютексом по порядку и возвращает число
+// принятых и отклонённые политикой аномалий. На первой ошибке Race
+// применение останавливается: события до неё остаются применёнными.
+// Вызывается и обработчиками, и при загрузке файла.
+func (s *Server) Apply(evs []event.Event) (accepted int, rejected []event.Event, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, ev := range evs {
+		switch err := s.race.Apply(ev); {
+		case errors.Is(err, competition.ErrRejected):
+			rejected = append(rejected, ev)
+		case err != nil:
+			return accepted, rejected, err
+		default:
+			accepted++
+		}
+		s.publishStandings()
+	}
+	return accepted, rejected, nil
+}
+
+func (s *Server) postEvents(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBody))
+	if err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, err)
+		return
+	}
+	var evs []event.Event
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		evs, err = decodeJSONEvents(body)
+	} else {
+		evs, err = decodeTextEvents(body)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	accepted, rejected, err := s.Apply(evs)
+	resp := struct {
+		Accepted int         `json:"accepted"`
+		Rejected []jsonEvent `json:"rejected"`
+		Error    string      `json:"error,omitempty"`
+	}{Accepted: accepted, Rejected: toJSON(rejected)}
+	status := http.StatusOK
+	switch {
+	case errors.Is(err, competition.ErrFinalized), errors.Is(err, competition.ErrOutOfOrder):
+		status = http.StatusConflict
+	case err != nil:
+		status = http.StatusBadRequest
+	}
+	if err != nil {
+		resp.Error = err.Error()
+	}
+	writeJSON(w, status, resp)
+}
+
+func decodeTextEvents(body []byte) ([]event.Event, error) {
+	var evs []event.Event
+	for ev, err := range event.NewReader(bytes.NewReader(body)).All() {
+		if err != nil {
+			return nil, err
+		}
+		evs = append(evs, ev)
+	}
+	return evs, nil
+}
+
+func decodeJSONEvents(body []byte) ([]event.Event, error) {
+	var list []jsonEvent
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &list); err != nil {
+			return nil, err
+		}
+	} else {
+		var one jsonEvent
+		if err := json.Unmarshal(trimmed, &one); err != nil {
+			return nil, err
+		}
+		list = []jsonEvent{one}
+	}
+	evs := make([]event.Event, len(list))
+	for i, je := range list {
+		ev, err := je.event()
+		if err != nil {
+			return nil, fmt.Errorf("событие %d: %w", i+1, err)
+		}
+		evs[i] = ev
+	}
+	return evs, nil
+}
+
+func (s *Server) postFinalize(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	from := len(s.outgoing)
+	s.race.Finalize()
+	s.publishStandings()
+	writeJSON(w, http.StatusOK, toJSON(s.outgoing[from:]))
+}
+
+func (s *Server) getStandings(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("format")
+	if name == "" {
+		name = "json"
+	}
+	renderer, err := report.RendererFor(name)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var buf bytes.Buffer
+	s.mu.Lock()
+	err = renderer.Render(&buf, report.NewResult(s.cfg, s.race.Competitors()))
+	s.mu.Unlock()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", contentTypes[name])
+	w.Write(buf.Bytes())
+}
+
+var contentTypes = map[string]string{
+	"json": "application/json",
+	"text": "text/plain; charset=utf-8",
+	"csv":  "text/csv; charset=utf-8",
+	"html": "text/html; charset=utf-8",
+	"xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+func (s *Server) getTimeline(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("неверный ID участника: %s", r.PathValue("id")))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.race.Competitors()[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("участник %d не найден", id))
+		return
+	}
+	var evs []event.Event
+	for _, ev := range s.stream {
+		if ev.CompetitorId == id {
+			evs = append(evs, ev)
+		}
+	}
+	writeJSON(w, http.StatusOK, struct {
+		ID        int         `json:"id"`
+		State     string      `json:"state"`
+		Events    []jsonEvent `json:"events"`
+		Truncated bool        `json:"truncated,omitempty"`
+	}{ID: id, State: c.State.String(), Events: toJSON(evs), Truncated: s.dropped[id]})
+}
+
+func (s *Server) getOutgoing(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeJSON(w, http.StatusOK, toJSON(s.outgoing))
+}
+
+// isOutgoing — событие сгенерировано гонкой (32 — дисквалификация, 33 — финиш).
+func isOutgoing(ev event.Event) bool {
+	return ev.EventId == 32 || ev.EventId == 33
+}
+
+// jsonEvent — событие в JSON: {"time", "eventId", "competitorId", "extraParams"}.
+// В ответах добавляется line — строка в формате входного файла.
+type jsonEvent struct {
+	Time         string `json:"time"`
+	EventId      int    `json:"eventId"`
+	CompetitorId int    `json:"competitorId"`
+	ExtraParams  string `json:"extraParams,omitempty"`
+	Line         string `json:"line,omitempty"`
+}
+
+func (je jsonEvent) event() (event.Event, error) {
+	t, err := config.ParseRowForDuration(je.Time)
+	if err != nil {
+		return event.Event{}, err
+	}
+	if je.EventId == 0 || je.CompetitorId == 0 {
+		return event.Event{}, errors.New("нужны eventId и competitorId")
+	}
+	return event.Event{Fixtime: t, EventId: je.EventId, CompetitorId: je.CompetitorId, ExtraParams: je.ExtraParams}, nil
+}
+
+func toJSON(evs []event.Event) []jsonEvent {
+	out := make([]jsonEvent, len(evs))
+	for i, ev := range evs {
+		out[i] = jsonEvent{
+			Time:         config.FormatClock(ev.Fixtime),
+			EventId:      ev.EventId,
+			CompetitorId: ev.CompetitorId,
+			ExtraParams:  ev.ExtraParams,
+			Line:         ev.String(),
+		}
+	}
+	return out
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, struct {
+		Error string `json:"error"`
+	}{err.Error()})
+}
@@ -0,0 +1,319 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ironywer/sunny_5_skiers/competition"
+	"github.com/ironywer/sunny_5_skiers/config"
+)
+
+func newTestServer(t *testing.T) (*Server, *httptest.Server) {
+	t.Helper()
+	cfg := &config.Config{
+		Laps: 1, LapLen: 1000, PenaltyLen: 100, FiringLines: 1,
+		Start:      10 * time.Hour,
+		StartDelta: 30 * time.Second,
+	}
+	s := New(cfg, competition.NewRace(cfg, nil))
+	ts := httptest.NewServer(s.Handler())
+	t.Cleanup(ts.Close)
+	return s, ts
+}
+
+func post(t *testing.T, url, contentType, body string) (int, map[string]any) {
+	t.Helper()
+	resp, err := http.Post(url, contentType, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var out map[string]any
+	json.NewDecoder(resp.Body).Decode(&out)
+	return resp.StatusCode, out
+}
+
+func get(t *testing.T, url string, v any) int {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatalf("decode %s: %v", url, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestServer(t *testing.T) {
+	_, ts := newTestServer(t)
+
+	text := `[09:30:00.000] 1 1
+[09:30:00.000] 1 2
+[09:31:00.000] 2 1 10:00:00.000
+[09:31:00.000] 2 2 10:20:00.000
+[10:00:00.000] 4 1`
+	if code, out := post(t, ts.URL+"/events", "text/plain", text); code != http.StatusOK || out["accepted"] != 5.0 {
+		t.Fatalf("POST text: %d %v", code, out)
+	}
+	single := `{"time": "10:10:00.000", "eventId": 10, "competitorId": 1}`
+	if code, out := post(t, ts.URL+"/events", "application/json", single); code != http.StatusOK || out["accepted"] != 1.0 {
+		t.Fatalf("POST json: %d %v", code, out)
+	}
+	if code, _ := post(t, ts.URL+"/events", "text/plain", "[10:11:00.000] 2 3 soon"); code != http.StatusBadRequest {
+		t.Errorf("bad start time: status %d; want 400", code)
+	}
+	if code, _ := post(t, ts.URL+"/events", "application/json", `[{"time": "x", "eventId": 1, "competitorId": 3}]`); code != http.StatusBadRequest {
+		t.Errorf("bad json time: status %d; want 400", code)
+	}
+	if code, out := post(t, ts.URL+"/events", "text/plain", "[10:10:00.000] 1 3\n[10:09:00.000] 1 4"); code != http.StatusConflict || out["accepted"] != 1.0 {
+		t.Errorf("event back in time: %d %v; want 409 with 1 accepted", code, out)
+	}
+
+	var standings struct {
+		Results []struct {
+			ID     int
+			Place  int
+			Status string
+		}
+	}
+	if code := get(t, ts.URL+"/standings", &standings); code != http.StatusOK {
+		t.Fatalf("GET standings: %d", code)
+	}
+	if len(standings.Results) != 3 || standings.Results[0].ID != 1 || standings.Results[0].Place != 1 ||
+		standings.Results[1].Status != "Racing" {
+		t.Errorf("standings = %+v", standings)
+	}
+
+	var timeline struct {
+		ID     int
+		State  string
+		Events []struct {
+			EventId int
+			Line    string
+		}
+	}
+	if code := get(t, ts.URL+"/competitors/1/timeline", &timeline); code != http.StatusOK {
+		t.Fatalf("GET timeline: %d", code)
+	}
+	if timeline.State != "Finished" || len(timeline.Events) != 5 || timeline.Events[4].Line != "[10:10:00.000] 33 1" {
+		t.Errorf("timeline = %+v", timeline)
+	}
+	if code := get(t, ts.URL+"/competitors/9/timeline", nil); code != http.StatusNotFound {
+		t.Errorf("unknown competitor: status %d; want 404", code)
+	}
+
+	if code, _ := post(t, ts.URL+"/finalize", "", ""); code != http.StatusOK {
+		t.Errorf("POST finalize: %d", code)
+	}
+	var outgoing []struct {
+		EventId      int
+		CompetitorId int
+	}
+	get(t, ts.URL+"/events/outgoing", &outgoing)
+	if len(outgoing) != 3 || outgoing[0].EventId != 33 || outgoing[1].EventId != 32 || outgoing[2].CompetitorId != 2 {
+		t.Errorf("outgoing = %+v", outgoing)
+	}
+	if code, _ := post(t, ts.URL+"/events", "text/plain", "[10:20:00.000] 1 5"); code != http.StatusConflict {
+		t.Errorf("POST after finalize: status %d; want 409", code)
+	}
+}
+
+func TestServerConcurrent(t *testing.T) {
+	s, ts := newTestServer(t)
+
+	var wg sync.WaitGroup
+	for i := 1; i <= 20; i++ {
+		wg.Add(2)
+		go func(id int) {
+			defer wg.Done()
+			post(t, ts.URL+"/events", "text/plain", fmt.Sprintf("[09:30:00.000] 1 %d", id))
+		}(i)
+		go func() {
+			defer wg.Done()
+			get(t, ts.URL+"/standings", new(any))
+		}()
+	}
+	wg.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if n := len(s.race.Competitors()); n != 20 {
+		t.Errorf("expected 20 competitors, got %d", n)
+	}
+}
+
+func TestServerHistoryLimit(t *testing.T) {
+	defer func(n int) { historyLimit = n }(historyLimit)
+	historyLimit = 2
+	_, ts := newTestServer(t)
+
+	text := `[09:30:00.000] 1 1
+[09:30:00.000] 1 2
+[09:31:00.000] 2 1 10:00:00.000
+[09:31:00.000] 2 2 10:00:30.000
+[10:00:00.000] 4 1
+[10:10:00.000] 10 1`
+	if code, out := post(t, ts.URL+"/events", "text/plain", text); code != http.StatusOK {
+		t.Fatalf("POST: %d %v", code, out)
+	}
+	post(t, ts.URL+"/finalize", "", "")
+
+	var timeline struct {
+		Events    []struct{ Line string }
+		Truncated bool
+	}
+	get(t, ts.URL+"/competitors/1/timeline", &timeline)
+	if !timeline.Truncated || len(timeline.Events) != 2 || timeline.Events[1].Line != "[10:10:00.000] 33 1" {
+		t.Errorf("timeline = %+v; want last 2 events, truncated", timeline)
+	}
+	var outgoing []struct{ Line string }
+	get(t, ts.URL+"/events/outgoing", &outgoing)
+	if len(outgoing) != 2 || outgoing[0].Line != "[10:01:00.000] 32 2" || outgoing[1].Line != "[10:10:00.000] 33 1" {
+		t.Errorf("outgoing = %+v; want 33 and 32 kept past the limit", outgoing)
+	}
+}
+
+// sseFrame — одно сообщение ленты /live.
+type sseFrame struct {
+	id, event, data string
+}
+
+// readFrames читает n сообщений, пропуская комментарии.
+func readFrames(t *testing.T, br *bufio.Reader, n int) []sseFrame {
+	t.Helper()
+	var frames []sseFrame
+	var f sseFrame
+	for len(frames) < n {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read live: %v (got %+v)", err, frames)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			if f.id != "" {
+				frames = append(frames, f)
+			}
+			f = sseFrame{}
+		case strings.HasPrefix(line, "id: "):
+			f.id = line[len("id: "):]
+		case strings.HasPrefix(line, "event: "):
+			f.event = line[len("event: "):]
+		case strings.HasPrefix(line, "data: "):
+			f.data = line[len("data: "):]
+		}
+	}
+	return frames
+}
+
+func openLive(t *testing.T, url, lastID string) *bufio.Reader {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, url+"/live", nil)
+	if lastID != "" {
+		req.Header.Set("Last-Event-ID", lastID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	return bufio.NewReader(resp.Body)
+}
+
+func TestLive(t *testing.T) {
+	_, ts := newTestServer(t)
+	br := openLive(t, ts.URL, "")
+
+	post(t, ts.URL+"/events", "text/plain", "[09:30:00.000] 1 1\n[09:31:00.000] 2 1 10:00:00.000")
+	post(t, ts.URL+"/events", "text/plain", "[10:00:00.000] 4 1\n[10:10:00.000] 10 1")
+
+	// 1 1: событие и первое положение; 2 1 и 4 1 положение не меняют;
+	// 10 1: событие, 33 и Finished.
+	frames := readFrames(t, br, 7)
+	var got []string
+	for _, f := range frames {
+		got = append(got, f.id+" "+f.event)
+	}
+	want := []string{
+		"1 incoming", "2 standings", "3 incoming", "4 incoming",
+		"5 incoming", "6 outgoing", "7 standings",
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("frames = %v; want %v", got, want)
+	}
+
+	var ev jsonEvent
+	if err := json.Unmarshal([]byte(frames[5].data), &ev); err != nil || ev.EventId != 33 || ev.Line != "[10:10:00.000] 33 1" {
+		t.Errorf("outgoing data = %s", frames[5].data)
+	}
+	var list []standing
+	if err := json.Unmarshal([]byte(frames[6].data), &list); err != nil || len(list) != 1 ||
+		list[0].Place != 1 || list[0].Status != "Finished" || list[0].Total != "00:10:00.000" {
+		t.Errorf("standings data = %s", frames[6].data)
+	}
+
+	// Переподключение: приходят только сообщения после Last-Event-ID.
+	br = openLive(t, ts.URL, "5")
+	frames = readFrames(t, br, 2)
+	if frames[0].id != "6" || frames[1].id != "7" {
+		t.Errorf("resume frames = %+v", frames)
+	}
+
+	if code := get(t, ts.URL+"/live?lastEventId=x", nil); code != http.StatusBadRequest {
+		t.Errorf("bad lastEventId: status %d; want 400", code)
+	}
+}
+
+func TestLiveResync(t *testing.T) {
+	defer func(n int) { historyLimit = n }(historyLimit)
+	historyLimit = 3
+	_, ts := newTestServer(t)
+
+	post(t, ts.URL+"/events", "text/plain", "[09:30:00.000] 1 1\n[09:31:00.000] 2 1 10:00:00.000")
+	post(t, ts.URL+"/events", "text/plain", "[10:00:00.000] 4 1\n[10:10:00.000] 10 1")
+
+	// Хранятся сообщения 5–7: после 4 продолжение обычное.
+	frames := readFrames(t, openLive(t, ts.URL, "4"), 3)
+	if frames[0].id != "5" || frames[2].id != "7" {
+		t.Errorf("resume frames = %+v", frames)
+	}
+
+	// Сообщения после 2 вытеснены: сначала resync, затем новые.
+	br := openLive(t, ts.URL, "2")
+	frames = readFrames(t, br, 1)
+	var list []standing
+	if f := frames[0]; f.id != "7" || f.event != updateResync ||
+		json.Unmarshal([]byte(f.data), &list) != nil || len(list) != 1 || list[0].Status != "Finished" {
+		t.Errorf("resync frame = %+v", f)
+	}
+	post(t, ts.URL+"/events", "text/plain", "[10:11:00.000] 1 2")
+	frames = readFrames(t, br, 2)
+	if frames[0].id != "8" || frames[0].event != updateIncoming || frames[1].id != "9" {
+		t.Errorf("frames after resync = %+v", frames)
+	}
+
+	// Last-Event-ID от прежнего запуска сервера больше последнего ID.
+	br = openLive(t, ts.URL, "500")
+	frames = readFrames(t, br, 1)
+	if f := frames[0]; f.id != "9" || f.event != updateResync {
+		t.Errorf("frame for a future Last-Event-ID = %+v; want resync 9", f)
+	}
+	post(t, ts.URL+"/events", "text/plain", "[10:12:00.000] 1 3")
+	if frames = readFrames(t, br, 1); frames[0].id != "10" {
+		t.Errorf("frames after resync = %+v", frames)
+	}
+}